@@ -0,0 +1,75 @@
+package bpe
+
+import (
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+// languageSplitterPrefix starts names of splitters made by LanguageSplitter, so they could be imported
+// without registration.
+const languageSplitterPrefix = "heuristic:"
+
+// abbreviations are bundled abbreviations by language. They are lower case and have no the last dot.
+// Initials and initialisms like "U.S." or "e.g." are recognized without dictionaries.
+var abbreviations = map[string][]string{
+	"en": {
+		"mr", "mrs", "ms", "dr", "prof", "sr", "jr", "st", "mt", "rev", "gen", "gov", "sen", "rep", "capt", "col",
+		"lt", "sgt", "vs", "etc", "cf", "al", "approx", "dept", "est", "fig", "inc", "ltd", "co", "corp", "vol",
+		"jan", "feb", "apr", "jun", "jul", "aug", "sep", "sept", "oct", "nov", "dec", "ph.d",
+	},
+	"de": {
+		"hr", "fr", "dr", "prof", "bzw", "ca", "evtl", "ggf", "inkl", "nr", "str", "usw", "vgl", "bspw", "abs",
+		"ff", "geb", "jh", "mio", "mrd", "tel", "zzgl", "sog", "jan", "feb", "apr", "jun", "jul", "aug", "sep",
+		"sept", "okt", "nov", "dez",
+	},
+	"ru": {
+		"г", "гг", "др", "пр", "см", "стр", "ул", "д", "им", "тыс", "млн", "млрд", "руб", "коп", "проф", "акад",
+		"доц", "напр", "т.е", "т.д", "т.п", "т.к", "и.о", "янв", "февр", "авг", "сент", "окт", "нояб", "дек",
+	},
+	"fr": {
+		"m", "mm", "mme", "mlle", "dr", "me", "st", "ste", "etc", "cf", "p.ex", "env", "av", "bd", "vol", "chap",
+		"janv", "févr", "avr", "juil", "sept", "oct", "nov", "déc",
+	},
+}
+
+// Abbreviations returns bundled abbreviations of the language: "en", "de", "ru" or "fr".
+// They could be extended and passed to AbbreviationSplitter.
+func Abbreviations(language string) ([]string, error) {
+	list, ok := abbreviations[language]
+	if !ok {
+		return nil, errors.Errorf("no abbreviations for language %q", language)
+	}
+
+	return append([]string(nil), list...), nil
+}
+
+// LanguageSplitter works like HeuristicSplitter but recognizes abbreviations of the given languages
+// with bundled dictionaries instead of guessing them. Splitter is imported without registration.
+func LanguageSplitter(languages ...string) (SentenceSplitter, error) {
+	var list []string
+
+	for _, language := range languages {
+		languageAbbreviations, err := Abbreviations(language)
+		if err != nil {
+			return nil, err
+		}
+
+		list = append(list, languageAbbreviations...)
+	}
+
+	return AbbreviationSplitter(languageSplitterPrefix+strings.Join(languages, ","), list...), nil
+}
+
+// AbbreviationSplitter works like HeuristicSplitter but recognizes the given abbreviations instead of guessing them.
+// Abbreviations are case insensitive, the last dot is optional. Splitter must be registered
+// with RegisterSentenceSplitter to import models which use it.
+func AbbreviationSplitter(name string, list ...string) SentenceSplitter {
+	set := make(map[string]struct{}, len(list))
+
+	for _, abbreviation := range list {
+		set[strings.ToLower(strings.TrimSuffix(abbreviation, "."))] = struct{}{}
+	}
+
+	return &heuristicSplitter{name: name, abbreviations: set}
+}
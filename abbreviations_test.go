@@ -0,0 +1,145 @@
+package bpe
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestLanguageSplitter(t *testing.T) {
+	english := "Dr. Smith met Bob. He said \"Hi.\" We waited... It was late.\n1. Go home\n2. Sleep"
+
+	tt := []struct {
+		name      string
+		languages []string
+		splitter  SentenceSplitter
+		text      string
+		expected  []string
+	}{
+		{
+			name:     "guessed abbreviations",
+			splitter: HeuristicSplitter(),
+			text:     english,
+			expected: []string{
+				"Dr. Smith met Bob. He said \"Hi.\" We waited...",
+				"It was late.",
+				"1. Go home\n",
+				"2. Sleep",
+			},
+		},
+		{
+			name:      "en",
+			languages: []string{"en"},
+			text:      english,
+			expected: []string{
+				"Dr. Smith met Bob.",
+				"He said \"Hi.\"",
+				"We waited...",
+				"It was late.",
+				"1. Go home\n",
+				"2. Sleep",
+			},
+		},
+		{
+			name:      "de",
+			languages: []string{"de"},
+			text:      "Das ist z.B. gut. Hr. Müller kommt.",
+			expected:  []string{"Das ist z.B. gut.", "Hr. Müller kommt."},
+		},
+		{
+			name:      "ru",
+			languages: []string{"ru"},
+			text:      "См. стр. 5. Это г. Москва.",
+			expected:  []string{"См. стр. 5.", "Это г. Москва."},
+		},
+		{
+			name:      "fr and en",
+			languages: []string{"fr", "en"},
+			text:      "Mme. Dupont et Mr. Brown arrivent. Ils partent.",
+			expected:  []string{"Mme. Dupont et Mr. Brown arrivent.", "Ils partent."},
+		},
+		{
+			name:     "user abbreviations",
+			splitter: AbbreviationSplitter("test_custom", "Approx.", "Bob"),
+			text:     "Bob. Marley sang. Approx. Ten times.",
+			expected: []string{"Bob. Marley sang.", "Approx. Ten times."},
+		},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			splitter := tc.splitter
+			if splitter == nil {
+				var err error
+
+				splitter, err = LanguageSplitter(tc.languages...)
+				if err != nil {
+					t.Fatalf("Unexpected error: %v", err)
+				}
+			}
+
+			scanner := bufio.NewScanner(strings.NewReader(tc.text))
+			scanner.Split(sentenceSplitFunc(splitter, false))
+
+			var actual []string
+			for scanner.Scan() {
+				actual = append(actual, scanner.Text())
+			}
+
+			if !reflect.DeepEqual(tc.expected, actual) {
+				t.Errorf("Expected: %q\nGot: %q\n", tc.expected, actual)
+			}
+		})
+	}
+}
+
+func TestLanguageSplitter_UnknownLanguage(t *testing.T) {
+	if _, err := LanguageSplitter("en", "xx"); err == nil {
+		t.Error("Error expected")
+	}
+}
+
+func TestLanguageSplitter_ExportImport(t *testing.T) {
+	splitter, err := LanguageSplitter("en", "de")
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	model, err := Train(context.Background(), strings.NewReader("Hr. Bob came."), WithSentenceSplitter(splitter))
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	buf := bytes.NewBuffer(nil)
+	if err := Export(model, buf); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	imported, err := Import(buf)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	if name := sentenceSplitterName(imported.splitter); name != "heuristic:en,de" {
+		t.Errorf("Unexpected splitter %q", name)
+	}
+
+	tokens, err := imported.Encode(strings.NewReader("Mr. Bob came. Hr. Bob came."))
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	var sentences int
+	for _, token := range tokens {
+		if token == BeginOfSentence {
+			sentences++
+		}
+	}
+
+	if sentences != 2 {
+		t.Errorf("Expected 2 sentences. Got: %v", tokens)
+	}
+}
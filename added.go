@@ -0,0 +1,260 @@
+package bpe
+
+import (
+	"bufio"
+	"bytes"
+	"sort"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// AddedToken is a user-defined token which is always emitted as a single token.
+// Encode looks for added tokens in the raw text before splitting it into sentences and words.
+type AddedToken struct {
+	Content string `json:"content"`
+
+	// SingleWord makes token match only when it isn't a part of a bigger word.
+	SingleWord bool `json:"single_word,omitempty"`
+
+	// LStrip and RStrip make encoder drop spaces on the left or on the right side of the token.
+	LStrip bool `json:"lstrip,omitempty"`
+	RStrip bool `json:"rstrip,omitempty"`
+}
+
+// AddTokens registers atomic tokens. New tokens get IDs after all existing ones.
+// Tokens which are already registered are skipped. It returns number of added tokens.
+// It must not be called concurrently with encoding.
+func (b *BPE) AddTokens(tokens ...AddedToken) int {
+	if b.ids == nil {
+		b.assignIDs(b.orderedVocab())
+	}
+
+	var added int
+
+	for _, token := range tokens {
+		if token.Content == "" || b.isAddedToken(token.Content) {
+			continue
+		}
+
+		b.added = append(b.added, token)
+		b.addID(token.Content)
+		added++
+	}
+
+	// The longest tokens are matched first.
+	sort.SliceStable(b.added, func(i, j int) bool {
+		return len(b.added[i].Content) > len(b.added[j].Content)
+	})
+
+	return added
+}
+
+// orderedAddedTokens returns added tokens ordered by ID.
+func (b *BPE) orderedAddedTokens() []AddedToken {
+	tokens := append([]AddedToken(nil), b.added...)
+
+	sort.Slice(tokens, func(i, j int) bool {
+		return b.ids[tokens[i].Content] < b.ids[tokens[j].Content]
+	})
+
+	return tokens
+}
+
+func (b *BPE) isAddedToken(token string) bool {
+	for _, added := range b.added {
+		if added.Content == token {
+			return true
+		}
+	}
+
+	return false
+}
+
+// textSegment is a part of text which is either plain text or an added token.
+type textSegment struct {
+	text  string
+	start int // Offset in the text.
+	added bool
+}
+
+// atomicTokens returns added tokens together with reserved ones. The longest tokens go first.
+func (b *BPE) atomicTokens() []AddedToken {
+	reserved := b.specialTokens().addedTokens()
+	if len(reserved) == 0 {
+		return b.added
+	}
+
+	tokens := append(append([]AddedToken(nil), b.added...), reserved...)
+	sort.SliceStable(tokens, func(i, j int) bool {
+		return len(tokens[i].Content) > len(tokens[j].Content)
+	})
+
+	return tokens
+}
+
+// splitAddedTokens splits text into plain text segments and atomic tokens which are added and reserved ones.
+func (b *BPE) splitAddedTokens(text string) []textSegment {
+	return splitAtomicTokens(text, b.atomicTokens())
+}
+
+// splitAtomicTokens splits text into plain text segments and the given tokens. Tokens must go longest first.
+func splitAtomicTokens(text string, tokens []AddedToken) []textSegment {
+	if len(tokens) == 0 {
+		return []textSegment{{text: text}}
+	}
+
+	var segments []textSegment
+	textStart := 0
+
+	for i := 0; i < len(text); {
+		token, ok := matchAtomicToken(tokens, text, i)
+		if !ok {
+			_, width := utf8.DecodeRuneInString(text[i:])
+			i += width
+
+			continue
+		}
+
+		left := text[textStart:i]
+		if token.LStrip {
+			left = strings.TrimRightFunc(left, unicode.IsSpace)
+		}
+
+		if left != "" {
+			segments = append(segments, textSegment{text: left, start: textStart})
+		}
+
+		segments = append(segments, textSegment{text: token.Content, start: i, added: true})
+		i += len(token.Content)
+
+		if token.RStrip {
+			i += len(text[i:]) - len(strings.TrimLeftFunc(text[i:], unicode.IsSpace))
+		}
+
+		textStart = i
+	}
+
+	if textStart < len(text) {
+		segments = append(segments, textSegment{text: text[textStart:], start: textStart})
+	}
+
+	return segments
+}
+
+// matchAtomicToken returns the longest of the tokens which starts at position i of the text.
+func matchAtomicToken(tokens []AddedToken, text string, i int) (AddedToken, bool) {
+	for _, token := range tokens {
+		if !strings.HasPrefix(text[i:], token.Content) {
+			continue
+		}
+
+		if token.SingleWord && !isWordBoundary(text, i, i+len(token.Content)) {
+			continue
+		}
+
+		return token, true
+	}
+
+	return AddedToken{}, false
+}
+
+// isWordBoundary checks that text[start:end] isn't surrounded by word characters.
+func isWordBoundary(text string, start, end int) bool {
+	if before, _ := utf8.DecodeLastRuneInString(text[:start]); start > 0 && isWordRune(before) {
+		return false
+	}
+
+	if after, _ := utf8.DecodeRuneInString(text[end:]); end < len(text) && isWordRune(after) {
+		return false
+	}
+
+	return true
+}
+
+func isWordRune(r rune) bool {
+	return unicode.IsLetter(r) || unicode.IsDigit(r) || r == '_'
+}
+
+// protectAddedTokens wraps split function so it never splits text inside of added and reserved tokens.
+func (b *BPE) protectAddedTokens(split bufio.SplitFunc) bufio.SplitFunc {
+	tokens := b.atomicTokens()
+	if len(tokens) == 0 {
+		return split
+	}
+
+	return func(data []byte, atEOF bool) (int, []byte, error) {
+		advance, token, err := split(data, atEOF)
+		if err != nil || token == nil {
+			return advance, token, err
+		}
+
+		tokenStart := advance - len(token)
+
+		for {
+			end, partial := atomicTokenAcross(tokens, data, advance)
+			if end == 0 {
+				break
+			}
+
+			if partial && !atEOF {
+				// Added token could be cut by the end of the buffer.
+				return 0, nil, nil
+			}
+
+			if partial || end >= len(data) {
+				advance = len(data)
+				break
+			}
+
+			nextAdvance, nextToken, err := split(data[end:], atEOF)
+			if err != nil {
+				return 0, nil, err
+			}
+
+			if nextToken == nil {
+				if !atEOF {
+					return 0, nil, nil
+				}
+
+				advance = len(data)
+
+				break
+			}
+
+			advance = end + nextAdvance
+		}
+
+		return advance, data[tokenStart:advance], nil
+	}
+}
+
+// atomicTokenAcross returns the end of one of the tokens which crosses the boundary.
+// The second value is true if the token is cut by the end of data. It returns 0 if there is no such token.
+func atomicTokenAcross(tokens []AddedToken, data []byte, boundary int) (end int, partial bool) {
+	for _, token := range tokens {
+		content := []byte(token.Content)
+
+		start := boundary - len(content) + 1
+		if start < 0 {
+			start = 0
+		}
+
+		for ; start < boundary; start++ {
+			tail := data[start:]
+			if len(tail) < len(content) {
+				if bytes.HasPrefix(content, tail) {
+					return len(data), true
+				}
+
+				continue
+			}
+
+			if bytes.HasPrefix(tail, content) && start+len(content) > end {
+				end = start + len(content)
+			}
+		}
+	}
+
+	return end, false
+}
@@ -0,0 +1,165 @@
+package bpe
+
+import (
+	"bytes"
+	"reflect"
+	"strings"
+	"testing"
+	"testing/iotest"
+)
+
+func TestBPE_AddTokens(t *testing.T) {
+	model := newModel([]string{"foo"})
+	vocabEnd := len(model.tokens)
+
+	added := model.AddTokens(
+		AddedToken{Content: "[URL]"},
+		AddedToken{Content: "<|endoftext|>"},
+		AddedToken{Content: "[URL]"},
+		AddedToken{Content: ""},
+	)
+	if added != 2 {
+		t.Errorf("Expected 2 added tokens. Got: %d", added)
+	}
+
+	for i, token := range []string{"[URL]", "<|endoftext|>"} {
+		if id, ok := model.TokenToID(token); !ok || id != vocabEnd+i {
+			t.Errorf("Token %q expected to have ID %d. Got: %d", token, vocabEnd+i, id)
+		}
+	}
+
+	if _, ok := model.vocab["[URL]"]; ok {
+		t.Error("Added tokens must not be part of the vocabulary used for segmentation")
+	}
+}
+
+func TestBPE_splitAddedTokens(t *testing.T) {
+	model := &BPE{}
+	model.AddTokens(
+		AddedToken{Content: "[URL]"},
+		AddedToken{Content: "[URL][URL]"},
+		AddedToken{Content: "cat", SingleWord: true},
+		AddedToken{Content: "<L>", LStrip: true},
+		AddedToken{Content: "<R>", RStrip: true},
+	)
+
+	tt := []struct {
+		text     string
+		expected []textSegment
+	}{
+		{
+			text:     "",
+			expected: nil,
+		},
+		{
+			text:     "no tokens",
+			expected: []textSegment{{text: "no tokens"}},
+		},
+		{
+			text: "see[URL]now",
+			expected: []textSegment{
+				{text: "see"},
+				{text: "[URL]", start: 3, added: true},
+				{text: "now", start: 8},
+			},
+		},
+		{
+			text: "[URL][URL][URL]",
+			expected: []textSegment{
+				{text: "[URL][URL]", added: true},
+				{text: "[URL]", start: 10, added: true},
+			},
+		},
+		{
+			text: "cat concatenate cat.",
+			expected: []textSegment{
+				{text: "cat", added: true},
+				{text: " concatenate ", start: 3},
+				{text: "cat", start: 16, added: true},
+				{text: ".", start: 19},
+			},
+		},
+		{
+			text: "a  <L>  b  <R>  c",
+			expected: []textSegment{
+				{text: "a"},
+				{text: "<L>", start: 3, added: true},
+				{text: "  b  ", start: 6},
+				{text: "<R>", start: 11, added: true},
+				{text: "c", start: 16},
+			},
+		},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.text, func(t *testing.T) {
+			actual := model.splitAddedTokens(tc.text)
+			if !reflect.DeepEqual(tc.expected, actual) {
+				t.Errorf("Expected: %v\nGot: %v\n", tc.expected, actual)
+			}
+		})
+	}
+}
+
+func TestBPE_Encode_AddedTokens(t *testing.T) {
+	model := newModel([]string{BeginOfWord + "foo" + EndOfWord, BeginOfWord + "bar" + EndOfWord, "." + EndOfWord})
+	model.AddTokens(AddedToken{Content: "<!END!>"}, AddedToken{Content: "[URL]"})
+
+	tokens, err := model.Encode(strings.NewReader("foo<!END!>bar [URL]. foo"))
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	// Added token isn't split into sentences even though it contains end of sentence symbols.
+	expected := []string{
+		BeginOfSentence,
+		BeginOfWord + "foo" + EndOfWord,
+		"<!END!>",
+		BeginOfWord + "bar" + EndOfWord,
+		"[URL]",
+		BeginOfWord, "." + EndOfWord,
+		EndOfSentence,
+		BeginOfSentence,
+		BeginOfWord + "foo" + EndOfWord,
+		EndOfSentence,
+	}
+
+	if !reflect.DeepEqual(expected, tokens) {
+		t.Errorf("Expected: %v\nGot: %v\n", expected, tokens)
+	}
+}
+
+func TestBPE_Encode_AddedTokenCutByBuffer(t *testing.T) {
+	model := newModel([]string{BeginOfWord + "a" + EndOfWord})
+	model.AddTokens(AddedToken{Content: "<!END!>"})
+
+	// Reader returns one byte at a time so the scanner sees partial token first.
+	tokens, err := model.Encode(iotest.OneByteReader(strings.NewReader("a <!END!>")))
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	expected := []string{BeginOfSentence, BeginOfWord + "a" + EndOfWord, "<!END!>", EndOfSentence}
+	if !reflect.DeepEqual(expected, tokens) {
+		t.Errorf("Expected: %v\nGot: %v\n", expected, tokens)
+	}
+}
+
+func TestAddedTokens_ExportImport(t *testing.T) {
+	model := newModel([]string{"foo", "bar"})
+	model.AddTokens(AddedToken{Content: "[URL]", SingleWord: true}, AddedToken{Content: "<|endoftext|>", RStrip: true})
+
+	buf := bytes.NewBuffer(nil)
+	if err := Export(model, buf); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	imported, err := Import(buf)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	if !reflect.DeepEqual(model, imported) {
+		t.Errorf("Expected: %v\nGot: %v\n", model, imported)
+	}
+}
@@ -0,0 +1,143 @@
+package bpe
+
+import (
+	"strings"
+	"unicode/utf8"
+)
+
+// WithLimitAlphabet limits number of distinct characters of the vocabulary alphabet.
+// The most frequent characters are kept, the others are encoded as unknown. Zero means no limit which is the default.
+// Alphabet is always included in vocabulary in full before other tokens even if it exceeds WithMaxNumberOfTokens.
+// Byte-level models always have all 256 bytes in the alphabet.
+// WithSubstringFrequency keeps the alphabet within WithMaxNumberOfTokens, so only its most frequent symbols are kept
+// if it doesn't fit.
+func WithLimitAlphabet(n int) TrainOption {
+	return func(opts *trainOptions) {
+		opts.LimitAlphabet = n
+	}
+}
+
+// WithInitialAlphabet adds characters to the alphabet even if they aren't present in the source.
+// They are kept regardless of WithLimitAlphabet. Ignored by byte-level models.
+func WithInitialAlphabet(characters string) TrainOption {
+	return func(opts *trainOptions) {
+		opts.InitialAlphabet += characters
+	}
+}
+
+// selectAlphabet returns symbols of the alphabet ordered by frequency.
+// Symbol is a character with optional word boundary markers.
+func selectAlphabet(symbolsFrequency tokensFrequencyTable, options *trainOptions) []string {
+	special := options.SpecialTokens
+	symbols := make(tokensFrequencyTable, len(symbolsFrequency))
+	characters := make(tokensFrequencyTable)
+
+	for symbol, count := range symbolsFrequency {
+		symbols[symbol] = count
+		characters[symbolCharacter(symbol, special)] += count
+	}
+
+	initial := make(map[string]struct{})
+
+	for _, r := range options.InitialAlphabet {
+		character := string(r)
+		initial[character] = struct{}{}
+
+		// Character of the initial alphabet could occur in any position of a word.
+		for _, symbol := range []string{
+			character,
+			special.BeginOfWord + character,
+			character + special.EndOfWord,
+			special.BeginOfWord + character + special.EndOfWord,
+		} {
+			if _, ok := symbols[symbol]; !ok {
+				symbols[symbol] = 0
+			}
+		}
+
+		if _, ok := characters[character]; !ok {
+			characters[character] = 0
+		}
+	}
+
+	if options.LimitAlphabet > 0 && len(characters) > options.LimitAlphabet {
+		kept := len(initial)
+
+		for _, character := range sortByFrequency(characters) {
+			if _, ok := initial[character]; ok {
+				continue
+			}
+
+			if kept >= options.LimitAlphabet {
+				delete(characters, character)
+				continue
+			}
+
+			kept++
+		}
+	}
+
+	for symbol := range symbols {
+		if _, ok := characters[symbolCharacter(symbol, special)]; !ok {
+			delete(symbols, symbol)
+		}
+	}
+
+	return sortByFrequency(symbols)
+}
+
+// symbolCharacter returns character of the alphabet symbol.
+func symbolCharacter(symbol string, special SpecialTokens) string {
+	if character := strings.TrimPrefix(symbol, special.BeginOfWord); character != "" {
+		symbol = character
+	}
+
+	if character := strings.TrimSuffix(symbol, special.EndOfWord); character != "" {
+		symbol = character
+	}
+
+	return symbol
+}
+
+// isAlphabetSymbol checks that token consists of a single character with optional word boundary markers.
+func isAlphabetSymbol(token string, special SpecialTokens) bool {
+	return utf8.RuneCountInString(symbolCharacter(token, special)) == 1
+}
+
+// substringVocab returns tokens to add to the vocabulary in the substring frequency mode: the alphabet followed by
+// the most frequent substrings which fit into the limit together with the vocabulary.
+// Symbols of the alphabet which occur less than the minimum frequency are skipped like other substrings.
+func substringVocab(tft tokensFrequencyTable, options *trainOptions, vocab map[string]struct{}) []string {
+	symbolsFrequency := make(tokensFrequencyTable)
+
+	for token, count := range tft {
+		if count >= options.MinFrequency && isAlphabetSymbol(token, options.SpecialTokens) {
+			symbolsFrequency[token] = count
+		}
+	}
+
+	candidates := selectAlphabet(symbolsFrequency, options)
+	candidates = append(candidates, mostFrequentTokens(tft, len(tft), options.MinFrequency)...)
+	added := make(map[string]struct{})
+
+	var tokens []string
+
+	for _, token := range candidates {
+		if len(vocab)+len(tokens) >= options.MaxNumberOfTokens {
+			break
+		}
+
+		if _, ok := vocab[token]; ok {
+			continue
+		}
+
+		if _, ok := added[token]; ok {
+			continue
+		}
+
+		added[token] = struct{}{}
+		tokens = append(tokens, token)
+	}
+
+	return tokens
+}
@@ -0,0 +1,128 @@
+package bpe
+
+import (
+	"bytes"
+	"context"
+	"io/ioutil"
+	"reflect"
+	"testing"
+)
+
+func TestSelectAlphabet(t *testing.T) {
+	symbols := tokensFrequencyTable{
+		BeginOfWord + "a": 5,
+		"a" + EndOfWord:   1,
+		"b":               3,
+		"c" + EndOfWord:   2,
+		"d":               1,
+	}
+
+	tt := []struct {
+		name     string
+		options  []TrainOption
+		expected []string
+	}{
+		{
+			name:     "all symbols",
+			expected: []string{BeginOfWord + "a", "b", "c" + EndOfWord, "a" + EndOfWord, "d"},
+		},
+		{
+			name:     "limit alphabet",
+			options:  []TrainOption{WithLimitAlphabet(2)},
+			expected: []string{BeginOfWord + "a", "b", "a" + EndOfWord},
+		},
+		{
+			name:    "initial alphabet",
+			options: []TrainOption{WithLimitAlphabet(3), WithInitialAlphabet("dx")},
+			expected: []string{
+				BeginOfWord + "a", "a" + EndOfWord, "d",
+				BeginOfWord + "d", BeginOfWord + "d" + EndOfWord,
+				BeginOfWord + "x", BeginOfWord + "x" + EndOfWord,
+				"d" + EndOfWord, "x", "x" + EndOfWord,
+			},
+		},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			options := defaultTrainOptions()
+			options.Apply(tc.options...)
+
+			actual := selectAlphabet(symbols, options)
+
+			if !reflect.DeepEqual(tc.expected, actual) {
+				t.Errorf("Expected: %v\nGot: %v\n", tc.expected, actual)
+			}
+		})
+	}
+}
+
+func TestTrain_NoUnknownsOnTrainingCorpus(t *testing.T) {
+	corpus, err := ioutil.ReadFile("example.txt")
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	tt := []struct {
+		name    string
+		options []TrainOption
+	}{
+		{
+			name:    "merges",
+			options: []TrainOption{WithMaxNumberOfTokens(50)},
+		},
+		{
+			name:    "substring frequency",
+			options: []TrainOption{WithMaxNumberOfTokens(150), WithSubstringFrequency()},
+		},
+		{
+			name:    "substring frequency with big vocabulary",
+			options: []TrainOption{WithMaxNumberOfTokens(1000), WithSubstringFrequency()},
+		},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			model, err := Train(context.Background(), bytes.NewReader(corpus), tc.options...)
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+
+			tokens, err := model.Encode(bytes.NewReader(corpus))
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+
+			for i, token := range tokens {
+				if token == UnknownToken {
+					t.Fatalf("Unknown token at %d: %v", i, tokens[max0(i-5):i+1])
+				}
+			}
+		})
+	}
+}
+
+func TestTrain_WithInitialAlphabet(t *testing.T) {
+	model, err := Train(context.Background(), bytes.NewReader([]byte("abc")), WithInitialAlphabet("xyz"))
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	tokens, err := model.Encode(bytes.NewReader([]byte("zyx")))
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	expected := []string{BeginOfSentence, BeginOfWord + "z", "y", "x" + EndOfWord, EndOfSentence}
+	if !reflect.DeepEqual(expected, tokens) {
+		t.Errorf("Expected: %v\nGot: %v\n", expected, tokens)
+	}
+}
+
+func max0(i int) int {
+	if i < 0 {
+		return 0
+	}
+
+	return i
+}
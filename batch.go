@@ -0,0 +1,100 @@
+package bpe
+
+import (
+	"context"
+	"fmt"
+	"runtime"
+	"strings"
+	"sync"
+)
+
+// EncodeBatch encodes documents concurrently. Tokens of every document are at its index.
+// Documents which fail to be encoded don't stop the others, their errors are reported with BatchError.
+// Up to WithBatchWorkers documents are encoded at once.
+func (b *BPE) EncodeBatch(ctx context.Context, documents []string, opts ...EncodeOption) ([][]string, error) {
+	options := defaultEncodeOptions()
+	options.Apply(opts...)
+
+	workers := options.Workers
+	if workers < 1 {
+		workers = runtime.GOMAXPROCS(0)
+	}
+
+	if workers > len(documents) {
+		workers = len(documents)
+	}
+
+	results := make([][]string, len(documents))
+	errs := make([]error, len(documents))
+	queue := make(chan int)
+	wg := sync.WaitGroup{}
+
+	for i := 0; i < workers; i++ {
+		wg.Add(1)
+
+		go func() {
+			defer wg.Done()
+
+			for i := range queue {
+				results[i], errs[i] = b.encodeTokens(strings.NewReader(documents[i]), options)
+			}
+		}()
+	}
+
+documentsLoop:
+	for i := range documents {
+		select {
+		case <-ctx.Done():
+			break documentsLoop
+		case queue <- i:
+		}
+	}
+
+	close(queue)
+	wg.Wait()
+
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	for _, err := range errs {
+		if err != nil {
+			return results, &BatchError{Errors: errs}
+		}
+	}
+
+	return results, nil
+}
+
+// WithBatchWorkers sets number of goroutines encoding documents of a batch.
+// Default is GOMAXPROCS.
+func WithBatchWorkers(n int) EncodeOption {
+	return func(opts *encodeOptions) {
+		opts.Workers = n
+	}
+}
+
+// BatchError reports documents of a batch which failed to be encoded. Tokens of the others are returned.
+type BatchError struct {
+	Errors []error // Error by document index. It's nil for documents encoded successfully.
+}
+
+func (e *BatchError) Error() string {
+	failed, first := 0, -1
+
+	for i, err := range e.Errors {
+		if err != nil {
+			failed++
+
+			if first < 0 {
+				first = i
+			}
+		}
+	}
+
+	if first < 0 {
+		return "no documents failed"
+	}
+
+	return fmt.Sprintf("%d of %d documents failed, document %d: %v", failed, len(e.Errors), first, e.Errors[first])
+}
@@ -0,0 +1,121 @@
+package bpe
+
+import (
+	"context"
+	"fmt"
+	"io/ioutil"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestBPE_EncodeBatch(t *testing.T) {
+	model := newTruncationModel()
+	documents := []string{"a b", "", "c d e\na", "e", "b b b"}
+
+	for _, workers := range []int{0, 1, 2, 10} {
+		t.Run(fmt.Sprintf("workers=%d", workers), func(t *testing.T) {
+			actual, err := model.EncodeBatch(context.Background(), documents, WithBatchWorkers(workers))
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+
+			if len(actual) != len(documents) {
+				t.Fatalf("Expected %d results. Got: %d", len(documents), len(actual))
+			}
+
+			for i, document := range documents {
+				expected, err := model.Encode(strings.NewReader(document))
+				if err != nil {
+					t.Fatalf("Unexpected error: %v", err)
+				}
+
+				if !reflect.DeepEqual(expected, actual[i]) {
+					t.Errorf("Document %d. Expected: %v\nGot: %v\n", i, expected, actual[i])
+				}
+			}
+		})
+	}
+}
+
+func TestBPE_EncodeBatch_Errors(t *testing.T) {
+	model := newTruncationModel()
+	model.splitter = NoSplitter()
+
+	// Sentence longer than the scanner buffer can't be encoded.
+	documents := []string{"a", strings.Repeat("a ", 70000), "b"}
+
+	actual, err := model.EncodeBatch(context.Background(), documents, WithBatchWorkers(2))
+
+	batchErr, ok := err.(*BatchError)
+	if !ok {
+		t.Fatalf("Expected batch error. Got: %v", err)
+	}
+
+	if batchErr.Errors[0] != nil || batchErr.Errors[1] == nil || batchErr.Errors[2] != nil {
+		t.Errorf("Expected the second document to fail. Got: %v", batchErr.Errors)
+	}
+
+	if len(actual[0]) == 0 || actual[1] != nil || len(actual[2]) == 0 {
+		t.Errorf("Expected tokens of the other documents. Got: %v", actual)
+	}
+}
+
+func TestBPE_EncodeBatch_Cancel(t *testing.T) {
+	model := newTruncationModel()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	_, err := model.EncodeBatch(ctx, []string{"a", "b"})
+	if err != context.Canceled {
+		t.Errorf("Expected cancellation. Got: %v", err)
+	}
+}
+
+func benchmarkDocuments(b *testing.B) (*BPE, []string) {
+	corpus, err := ioutil.ReadFile("example.txt")
+	if err != nil {
+		b.Fatalf("Unexpected error: %v", err)
+	}
+
+	model, err := Train(context.Background(), strings.NewReader(string(corpus)))
+	if err != nil {
+		b.Fatalf("Unexpected error: %v", err)
+	}
+
+	var documents []string
+	for i := 0; i < 20; i++ {
+		documents = append(documents, strings.SplitAfter(string(corpus), ". ")...)
+	}
+
+	return model, documents
+}
+
+func BenchmarkBPE_Encode(b *testing.B) {
+	model, documents := benchmarkDocuments(b)
+	b.ResetTimer()
+
+	for i := 0; i < b.N; i++ {
+		for _, document := range documents {
+			if _, err := model.Encode(strings.NewReader(document)); err != nil {
+				b.Fatalf("Unexpected error: %v", err)
+			}
+		}
+	}
+}
+
+func BenchmarkBPE_EncodeBatch(b *testing.B) {
+	model, documents := benchmarkDocuments(b)
+
+	for _, workers := range []int{1, 2, 4, 8} {
+		b.Run(fmt.Sprintf("workers=%d", workers), func(b *testing.B) {
+			for i := 0; i < b.N; i++ {
+				_, err := model.EncodeBatch(context.Background(), documents, WithBatchWorkers(workers))
+				if err != nil {
+					b.Fatalf("Unexpected error: %v", err)
+				}
+			}
+		})
+	}
+}
@@ -5,6 +5,7 @@ import (
 	"io"
 	"sort"
 	"strings"
+	"unicode/utf8"
 
 	"github.com/pkg/errors"
 )
@@ -14,6 +15,16 @@ var defaultTokensCap = 8
 type BPE struct {
 	maxTokenLength int
 	vocab          map[string]struct{} // Set with fast vocab search.
+	merges         map[mergePair]int   // Merge rank by pair. Empty for models trained without merges.
+	tokens         []string            // Token by ID.
+	ids            map[string]int      // ID by token.
+	byteLevel      bool                // Model works with bytes and keeps spaces.
+	special        SpecialTokens       // Defaults are used for empty fields.
+	added          []AddedToken        // User-defined atomic tokens ordered by length.
+	statistics     *trainingStatistics // Is kept only if requested for further training.
+	normalizers    []Normalizer        // Are applied to every sentence before encoding.
+	preTokenizers  []PreTokenizer      // Split words before encoding.
+	splitter       SentenceSplitter    // Splits text into sentences. Nil means the default one.
 }
 
 type weightedToken struct {
@@ -21,76 +32,424 @@ type weightedToken struct {
 	Weight int
 }
 
-func (b *BPE) Encode(r io.Reader) ([]string, error) {
-	scanner := bufio.NewScanner(r)
-	scanner.Split(scanSentences)
-	tokens := make([]string, 0, defaultTokensCap)
+// Encode splits text from r into tokens.
+func (b *BPE) Encode(r io.Reader, opts ...EncodeOption) ([]string, error) {
+	options := defaultEncodeOptions()
+	options.Apply(opts...)
+
+	return b.encodeTokens(r, options)
+}
+
+// encodeTokens splits text from r into tokens with already applied options.
+func (b *BPE) encodeTokens(r io.Reader, options *encodeOptions) ([]string, error) {
+	if options.fixedLength() {
+		encoding, err := b.encodeDetailed(r, options)
+		if err != nil {
+			return nil, err
+		}
+
+		encoding, err = b.fit(encoding, options)
+		if err != nil {
+			return nil, err
+		}
+
+		return encoding.Strings(), nil
+	}
+
+	result, err := b.encode(r, options, false)
+	if err != nil {
+		return nil, err
+	}
+
+	return result.tokens, nil
+}
 
-	for scanner.Scan() {
+// encode splits text from r into tokens. Details about tokens are collected if detailed is set.
+func (b *BPE) encode(r io.Reader, options *encodeOptions, detailed bool) (*encoded, error) {
+	var sentenceStart sourcePosition
+
+	scanner := b.sentenceScanner(r, options, detailed, &sentenceStart)
+	result := &encoded{
+		tokens:   make([]string, 0, defaultTokensCap),
+		detailed: detailed,
+	}
+
+	for ; scanner.Scan(); result.sentence++ {
 		sentence := scanner.Text()
-		b.encodeSentence(&tokens, sentence)
+		first := len(result.tokens)
+		b.encodeSentence(result, sentence, options)
+		result.locate(first, sentence, sentenceStart)
 	}
 
 	if err := scanner.Err(); err != nil && err != io.EOF {
 		return nil, errors.Wrap(err, "file scan")
 	}
 
-	return tokens, nil
+	return result, nil
+}
+
+// sentenceScanner returns scanner of sentences from r. If detailed is set position of the last sentence
+// in the source is tracked.
+func (b *BPE) sentenceScanner(
+	r io.Reader,
+	options *encodeOptions,
+	detailed bool,
+	sentenceStart *sourcePosition,
+) *bufio.Scanner {
+	splitter := b.splitter
+	if options.SentenceSplitter != nil {
+		splitter = options.SentenceSplitter
+	}
+
+	split := sentenceSplitFunc(splitter, b.byteLevel)
+
+	split = b.protectAddedTokens(split)
+
+	if detailed {
+		split = trackSentences(split, sentenceStart)
+	}
+
+	scanner := bufio.NewScanner(r)
+	scanner.Split(split)
+
+	// Buffer grows up to the limit only if sentences need it.
+	initialSize := options.ScanBufferSize
+	if initialSize > bufio.MaxScanTokenSize {
+		initialSize = bufio.MaxScanTokenSize
+	}
+
+	scanner.Buffer(make([]byte, 0, initialSize), options.ScanBufferSize)
+
+	return scanner
+}
+
+func defaultEncodeOptions() *encodeOptions {
+	return &encodeOptions{
+		ScanBufferSize: maxScanBufferSize,
+	}
+}
+
+type encodeOptions struct {
+	CollapseUnknowns bool
+	ByteFallback     bool
+	SentenceSplitter SentenceSplitter
+	MaxLength        int
+	Truncation       TruncationStrategy
+	Stride           int
+	Padding          bool
+	PaddingSide      PaddingSide
+	PadToken         string
+	PadToMultipleOf  int
+	Workers          int
+	ScanBufferSize   int
 }
 
-// Target is a pointer to slice of tokens because it helps avoid unnecessary memory allocations.
-func (b *BPE) encodeSentence(target *[]string, sentence string) {
-	*target = append(*target, BeginOfSentence)
-	words := strings.Fields(sentence)
-	for _, word := range words {
-		b.encodeWord(target, word)
+func (o *encodeOptions) Apply(opts ...EncodeOption) {
+	for _, opt := range opts {
+		opt(o)
+	}
+}
+
+type EncodeOption func(opts *encodeOptions)
+
+// WithCollapsedUnknowns makes encoder emit a single unknown token for the run of unknown characters
+// instead of one unknown token per character.
+func WithCollapsedUnknowns() EncodeOption {
+	return func(opts *encodeOptions) {
+		opts.CollapseUnknowns = true
 	}
-	*target = append(*target, EndOfSentence)
 }
 
-func (b *BPE) encodeWord(target *[]string, word string) {
-	word = BeginOfWord + word + EndOfWord // TODO use special tokens from BPE.
+// WithByteFallback makes encoder represent characters out of vocabulary with byte tokens like <0xE2>
+// instead of unknown token. Decode turns byte tokens back into the original text.
+func WithByteFallback() EncodeOption {
+	return func(opts *encodeOptions) {
+		opts.ByteFallback = true
+	}
+}
+
+// WithEncodeScanBufferSize sets the maximum size of a sentence in bytes. Encoding fails on longer sentences.
+// Default is 64KB, so text without sentence boundaries like minified code may need a bigger one.
+func WithEncodeScanBufferSize(size int) EncodeOption {
+	return func(opts *encodeOptions) {
+		opts.ScanBufferSize = size
+	}
+}
+
+// Target is a pointer because it helps avoid unnecessary memory allocations.
+// Spans of tokens are offsets in the sentence.
+func (b *BPE) encodeSentence(target *encoded, sentence string, options *encodeOptions) {
+	special := b.specialTokens()
+	target.appendSpecial(special.BeginOfSentence)
+
+	for _, segment := range b.splitAddedTokens(sentence) {
+		if segment.added {
+			target.startWord(segment.start)
+			target.append(segment.text, 0, len(segment.text))
+
+			continue
+		}
+
+		first := len(target.tokens)
+		text, textAlignment := normalizeAligned(segment.text, b.normalizers, target.detailed)
+		b.encodeText(target, text, options)
+		target.realign(first, textAlignment, segment.start)
+	}
+
+	target.appendSpecial(special.EndOfSentence)
+}
+
+// encodeText splits text into words and encodes them. Spans of tokens are offsets in the text.
+func (b *BPE) encodeText(target *encoded, text string, options *encodeOptions) {
+	special := b.specialTokens()
+	wordEnd := 0
+
+	for _, word := range preTokenize(text, b.preTokenizers, special, b.byteLevel) {
+		// Words are parts of the text which go in order.
+		if i := strings.Index(text[wordEnd:], word); i >= 0 {
+			wordEnd += i
+		}
+
+		target.startWord(wordEnd)
+		wordEnd += len(word)
+
+		b.encodeWord(target, word, options)
+	}
+}
+
+// encodeWord splits word into the longest tokens from the vocabulary.
+// Word is never split inside of a rune or a word boundary marker. Spans of tokens are offsets in the word.
+func (b *BPE) encodeWord(target *encoded, word string, options *encodeOptions) {
+	if len(b.merges) > 0 || b.byteLevel {
+		b.encodeWordWithMerges(target, word, options)
+
+		return
+	}
+
+	special := b.specialTokens()
+	marked := newMarkedWord(word, special)
+	word = special.BeginOfWord + word + special.EndOfWord
+	boundaries := unitBoundaries(word, special)
+	unknown := unknownAppender(options, special)
+
+	// Indexes of boundaries of the current token.
 	tokenStart := 0
+	lastUnit := len(boundaries) - 2
+	afterUnknown := false
 
-tokenLoop:
-	for tokenStart < len(word) {
-		tokenEnd := len(word)
+	for tokenStart <= lastUnit {
+		tokenEnd := b.longestToken(word, boundaries, tokenStart)
+		if tokenEnd > 0 {
+			unknown.Reset()
+			afterUnknown = false
+			start, end := marked.span(boundaries[tokenStart], boundaries[tokenEnd])
+			target.append(word[boundaries[tokenStart]:boundaries[tokenEnd]], start, end)
+			tokenStart = tokenEnd
 
-		if tokenEnd-tokenStart > b.maxTokenLength {
-			tokenEnd = tokenStart + b.maxTokenLength
+			continue
 		}
 
-		for ; tokenEnd != tokenStart; tokenEnd-- {
-			token := word[tokenStart:tokenEnd]
-			_, ok := b.vocab[token]
+		// Word boundary markers are never unknown by themselves. They go with unknown rune next to them
+		// or are emitted as is next to a token.
+		unitEnd := tokenStart + 1
+		start, end := marked.span(boundaries[tokenStart], boundaries[unitEnd])
 
-			if ok {
-				*target = append(*target, token)
-				tokenStart += len(token)
-				continue tokenLoop
+		switch {
+		case tokenStart == 0 && b.longestToken(word, boundaries, 1) == 0:
+			unitEnd = 2
+			start, end = marked.span(boundaries[tokenStart], boundaries[unitEnd])
+		case tokenStart == lastUnit && afterUnknown:
+			if options.ByteFallback {
+				target.append(special.EndOfWord, start, end)
 			}
+
+			tokenStart = unitEnd
+
+			continue
+		case tokenStart == 0 || tokenStart == lastUnit:
+			target.append(word[boundaries[tokenStart]:boundaries[unitEnd]], start, end)
+			tokenStart = unitEnd
+
+			continue
+		}
+
+		unknown.Append(target, word[boundaries[tokenStart]:boundaries[unitEnd]], start, end)
+		afterUnknown = true
+		tokenStart = unitEnd
+	}
+}
+
+// markedWord converts offsets in the word with boundary markers to offsets in the word itself.
+type markedWord struct {
+	prefix int // Length of begin of word marker.
+	length int // Length of the word without markers.
+}
+
+func newMarkedWord(word string, special SpecialTokens) markedWord {
+	return markedWord{prefix: len(special.BeginOfWord), length: len(word)}
+}
+
+// span returns span of the word which is covered by the span of the marked word. Markers cover nothing.
+func (m markedWord) span(start, end int) (int, int) {
+	return m.clamp(start - m.prefix), m.clamp(end - m.prefix)
+}
+
+func (m markedWord) clamp(offset int) int {
+	if offset < 0 {
+		return 0
+	}
+
+	if offset > m.length {
+		return m.length
+	}
+
+	return offset
+}
+
+// longestToken returns index of the end boundary of the longest vocabulary token which starts at the given one.
+// End of word marker is never a token by itself, so tokens which leave it alone are taken only if there is no other.
+// It returns 0 if there is no such token.
+func (b *BPE) longestToken(word string, boundaries []int, tokenStart int) int {
+	fallback := 0
+
+	// Tokens longer than maxTokenLength aren't in vocabulary, so the scan starts from the furthest boundary within it.
+	last := sort.Search(len(boundaries), func(i int) bool {
+		return boundaries[i]-boundaries[tokenStart] > b.maxTokenLength
+	}) - 1
+
+	for tokenEnd := last; tokenEnd > tokenStart; tokenEnd-- {
+		token := word[boundaries[tokenStart]:boundaries[tokenEnd]]
+		if _, ok := b.vocab[token]; !ok {
+			continue
+		}
+
+		if tokenEnd != len(boundaries)-2 {
+			return tokenEnd
 		}
 
-		*target = append(*target, UnknownToken)
-		tokenStart++
+		fallback = tokenEnd
+	}
+
+	return fallback
+}
+
+// unitBoundaries returns byte offsets of the units of marked word: word boundary markers and runes.
+// The first offset is always 0 and the last one is the length of word.
+func unitBoundaries(word string, special SpecialTokens) []int {
+	start := len(special.BeginOfWord)
+	end := len(word) - len(special.EndOfWord)
+	boundaries := make([]int, 0, len(word)+1)
+	boundaries = append(boundaries, 0, start)
+
+	for i := range word[start:end] {
+		if i > 0 {
+			boundaries = append(boundaries, start+i)
+		}
+	}
+
+	return append(boundaries, end, len(word))
+}
+
+// unknownRun appends unknown tokens. It appends only one token per run of unknown units if collapse is set.
+// Units are represented with byte tokens instead if byte fallback is set.
+type unknownRun struct {
+	collapse     bool
+	byteFallback bool
+	special      SpecialTokens
+	appended     bool
+}
+
+func unknownAppender(options *encodeOptions, special SpecialTokens) *unknownRun {
+	return &unknownRun{
+		collapse:     options.CollapseUnknowns,
+		byteFallback: options.ByteFallback,
+		special:      special,
+	}
+}
+
+// Append appends unknown token for the unit. Start and end are span of the unit.
+func (u *unknownRun) Append(target *encoded, unit string, start, end int) {
+	if u.byteFallback {
+		appendByteFallback(target, unit, u.special, start, end)
+		return
+	}
+
+	if u.collapse && u.appended {
+		target.extend(end)
+		return
+	}
+
+	target.append(u.special.Unknown, start, end)
+	u.appended = true
+}
+
+// Reset marks the end of the run.
+func (u *unknownRun) Reset() {
+	u.appended = false
+}
+
+// encodeWordWithMerges applies learned merges in order of their rank.
+// Symbols left out of the vocabulary are replaced with unknown token.
+func (b *BPE) encodeWordWithMerges(target *encoded, word string, options *encodeOptions) {
+	special := b.specialTokens()
+	unknown := unknownAppender(options, special)
+	marked := newMarkedWord(word, special)
+	offset := 0 // Offset of the symbol in the marked word or in the word itself for byte-level models.
+
+	for _, symbol := range b.applyMerges(splitSymbols(word, b.byteLevel, special)) {
+		length := len(symbol)
+		if b.byteLevel {
+			length = utf8.RuneCountInString(symbol) // Every rune of byte-level symbol is a byte of the word.
+		}
+
+		start, end := offset, offset+length
+		offset = end
+
+		if !b.byteLevel {
+			start, end = marked.span(start, end)
+		}
+
+		if _, ok := b.vocab[symbol]; !ok {
+			unknown.Append(target, symbol, start, end)
+			continue
+		}
+
+		unknown.Reset()
+		target.append(symbol, start, end)
 	}
 }
 
 // Decode todo description.
 // Error in response added for potential future usages to keep backward compatibility.
 func (b *BPE) Decode(tokens []string) (string, error) {
+	if b.byteLevel {
+		return b.decodeBytes(tokens), nil
+	}
+
 	builder := strings.Builder{}
+	special := b.specialTokens()
 
 	for _, token := range tokens {
+		if value, ok := parseByteToken(token); ok {
+			builder.WriteByte(value)
+			continue
+		}
+
+		// Reserved and added tokens are decoded as separate words.
+		if special.isReserved(token) || b.isAddedToken(token) {
+			builder.WriteByte(' ')
+			builder.WriteString(token)
+			continue
+		}
+
 		// Skip special tokens.
-		// TODO Use special tokens from BPE.
-		token = strings.TrimSuffix(token, BeginOfSentence)
-		token = strings.TrimSuffix(token, EndOfSentence)
-		token = strings.TrimSuffix(token, EndOfWord)
+		token = strings.TrimSuffix(token, special.BeginOfSentence)
+		token = strings.TrimSuffix(token, special.EndOfSentence)
+		token = strings.TrimSuffix(token, special.EndOfWord)
 
-		if strings.HasPrefix(token, BeginOfWord) {
+		if strings.HasPrefix(token, special.BeginOfWord) {
 			builder.WriteByte(' ')
-			token = token[len(BeginOfWord):]
+			token = token[len(special.BeginOfWord):]
 		}
 
 		_, err := builder.WriteString(token)
@@ -102,10 +461,48 @@ func (b *BPE) Decode(tokens []string) (string, error) {
 	return strings.TrimSpace(builder.String()), nil
 }
 
+// decodeBytes restores the original text from byte-level tokens. Spaces are kept as is.
+func (b *BPE) decodeBytes(tokens []string) string {
+	result := make([]byte, 0, len(tokens))
+	special := b.specialTokens()
+
+	for _, token := range tokens {
+		switch token {
+		case special.BeginOfSentence, special.EndOfSentence, special.Unknown:
+			continue
+		}
+
+		if special.isReserved(token) || b.isAddedToken(token) {
+			result = append(result, token...)
+			continue
+		}
+
+		if value, ok := parseByteToken(token); ok {
+			result = append(result, value)
+			continue
+		}
+
+		result = append(result, fromByteLevel(token)...)
+	}
+
+	return string(result)
+}
+
 func newModelFromTokensFrequencyTable(tft tokensFrequencyTable, tokensLimit int) *BPE {
+	return newModel(mostFrequentTokens(tft, tokensLimit, 0))
+}
+
+// mostFrequentTokens returns up to tokensLimit tokens which occur at least minFrequency times ordered by frequency.
+// Tokens with equal frequency are ordered by byte length, the longest first, and then lexicographically,
+// so the result doesn't depend on the map iteration order.
+func mostFrequentTokens(tft tokensFrequencyTable, tokensLimit, minFrequency int) []string {
 	tokensListWithWeights := make([]weightedToken, 0, len(tft))
 
 	for t, w := range tft {
+		if w < minFrequency {
+			continue
+		}
+
 		token := t
 		tokensListWithWeights = append(tokensListWithWeights, weightedToken{
 			Token:  &token,
@@ -114,19 +511,41 @@ func newModelFromTokensFrequencyTable(tft tokensFrequencyTable, tokensLimit int)
 	}
 
 	sort.Slice(tokensListWithWeights, func(i, j int) bool {
-		return tokensListWithWeights[i].Weight > tokensListWithWeights[j].Weight
+		a, b := tokensListWithWeights[i], tokensListWithWeights[j]
+		if a.Weight != b.Weight {
+			return a.Weight > b.Weight
+		}
+
+		if len(*a.Token) != len(*b.Token) {
+			return len(*a.Token) > len(*b.Token)
+		}
+
+		return *a.Token < *b.Token
 	})
 
 	if len(tokensListWithWeights) > tokensLimit {
 		tokensListWithWeights = tokensListWithWeights[:tokensLimit]
 	}
 
-	var maxTokenLength int
-	vocab := make(map[string]struct{}, len(tokensListWithWeights))
-
+	tokens := make([]string, 0, len(tokensListWithWeights))
 	for _, t := range tokensListWithWeights {
-		token := *t.Token
+		tokens = append(tokens, *t.Token)
+	}
+
+	return tokens
+}
+
+// newModel creates model with given vocabulary and default special tokens.
+func newModel(tokens []string) *BPE {
+	return newModelWithSpecialTokens(tokens, DefaultSpecialTokens())
+}
 
+// newModelWithSpecialTokens creates model with given vocabulary. Token IDs follow the order of tokens.
+func newModelWithSpecialTokens(tokens []string, special SpecialTokens) *BPE {
+	var maxTokenLength int
+	vocab := make(map[string]struct{}, len(tokens))
+
+	for _, token := range tokens {
 		// TODO consider removing it and using value from config.
 		// Need to check necessity for this change with benchmarks.
 		tokenLength := len(token)
@@ -137,8 +556,12 @@ func newModelFromTokensFrequencyTable(tft tokensFrequencyTable, tokensLimit int)
 		vocab[token] = struct{}{}
 	}
 
-	return &BPE{
+	model := &BPE{
 		maxTokenLength: maxTokenLength,
 		vocab:          vocab,
+		special:        special.withDefaults(),
 	}
+	model.assignIDs(tokens)
+
+	return model
 }
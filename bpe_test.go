@@ -11,6 +11,7 @@ func TestBPE_Encode(t *testing.T) {
 		name      string
 		b         *BPE
 		in        io.Reader
+		options   []EncodeOption
 		expected  []string
 		withError bool
 	}{
@@ -51,6 +52,26 @@ func TestBPE_Encode(t *testing.T) {
 			b:        &BPE{},
 			expected: []string{},
 		},
+		{
+			name: "end of word marker isn't left alone",
+			in:   strings.NewReader("cab ab"),
+			expected: []string{
+				BeginOfSentence,
+				BeginOfWord + "c", "a", "b" + EndOfWord,
+				BeginOfWord + "a", "b" + EndOfWord,
+				EndOfSentence,
+			},
+			b: &BPE{
+				maxTokenLength: 16,
+				vocab: map[string]struct{}{
+					BeginOfWord + "c": {},
+					BeginOfWord + "a": {},
+					"ab":              {},
+					"a":               {},
+					"b" + EndOfWord:   {},
+				},
+			},
+		},
 		{
 			name: "foo",
 			in:   strings.NewReader("foo"),
@@ -99,11 +120,10 @@ func TestBPE_Encode(t *testing.T) {
 		{
 			name: "empty vocab",
 			in:   strings.NewReader("foo"),
-			// <w>foo</w>
+			// <w>f o o</w>
 			expected: []string{
 				BeginOfSentence,
-				UnknownToken, UnknownToken, UnknownToken, UnknownToken, UnknownToken,
-				UnknownToken, UnknownToken, UnknownToken, UnknownToken, UnknownToken,
+				UnknownToken, UnknownToken, UnknownToken,
 				EndOfSentence,
 			},
 			b: &BPE{
@@ -150,11 +170,122 @@ func TestBPE_Encode(t *testing.T) {
 				},
 			},
 		},
+		{
+			name: "mixed scripts",
+			in:   strings.NewReader("мир 世界 wörld 🙂"),
+			expected: []string{
+				BeginOfSentence,
+				BeginOfWord + "ми", UnknownToken, EndOfWord,
+				BeginOfWord, UnknownToken, "界" + EndOfWord,
+				BeginOfWord + "w", "ö", UnknownToken, UnknownToken, "d" + EndOfWord,
+				BeginOfWord, UnknownToken, EndOfWord,
+				EndOfSentence,
+			},
+			b: &BPE{
+				maxTokenLength: 64,
+				vocab: map[string]struct{}{
+					BeginOfWord:        {},
+					EndOfWord:          {},
+					BeginOfWord + "ми": {},
+					"界" + EndOfWord:    {},
+					BeginOfWord + "w":  {},
+					"ö":                {},
+					"d" + EndOfWord:    {},
+					// Partial runes must never be matched.
+					"\xd1":     {},
+					"\xf0\x9f": {},
+					"\xe4\xb8": {},
+				},
+			},
+		},
+		{
+			name: "unknown rune goes with word boundary markers",
+			in:   strings.NewReader("ж a"),
+			expected: []string{
+				BeginOfSentence,
+				UnknownToken,
+				BeginOfWord + "a", EndOfWord,
+				EndOfSentence,
+			},
+			b: &BPE{
+				maxTokenLength: 64,
+				vocab: map[string]struct{}{
+					BeginOfWord + "a": {},
+					"a" + EndOfWord:   {},
+					"b":               {},
+				},
+			},
+		},
+		{
+			name:    "collapsed unknowns",
+			in:      strings.NewReader("wörld тест"),
+			options: []EncodeOption{WithCollapsedUnknowns()},
+			expected: []string{
+				BeginOfSentence,
+				BeginOfWord + "w", "ö", UnknownToken, "d" + EndOfWord,
+				UnknownToken,
+				EndOfSentence,
+			},
+			b: &BPE{
+				maxTokenLength: 64,
+				vocab: map[string]struct{}{
+					BeginOfWord + "w": {},
+					"ö":               {},
+					"d" + EndOfWord:   {},
+				},
+			},
+		},
+		{
+			name:    "collapsed unknowns with merges",
+			in:      strings.NewReader("abba"),
+			options: []EncodeOption{WithCollapsedUnknowns()},
+			expected: []string{
+				BeginOfSentence,
+				BeginOfWord + "a", UnknownToken, "a" + EndOfWord,
+				EndOfSentence,
+			},
+			b: &BPE{
+				maxTokenLength: 64,
+				vocab: map[string]struct{}{
+					BeginOfWord + "a": {},
+					"a" + EndOfWord:   {},
+				},
+				merges: map[mergePair]int{
+					{Left: "x", Right: "y"}: 0,
+				},
+			},
+		},
+		{
+			name: "merges by rank",
+			in:   strings.NewReader("abc ad"),
+			expected: []string{
+				BeginOfSentence,
+				BeginOfWord + "a",
+				"bc" + EndOfWord,
+				BeginOfWord + "a",
+				UnknownToken,
+				EndOfSentence,
+			},
+			b: &BPE{
+				maxTokenLength: 64,
+				vocab: map[string]struct{}{
+					BeginOfWord + "a":  {},
+					"b":                {},
+					"c" + EndOfWord:    {},
+					"bc" + EndOfWord:   {},
+					BeginOfWord + "ab": {},
+				},
+				merges: map[mergePair]int{
+					{Left: "b", Right: "c" + EndOfWord}:   0,
+					{Left: BeginOfWord + "a", Right: "b"}: 1,
+				},
+			},
+		},
 	}
 
 	for _, tc := range tt {
 		t.Run(tc.name, func(t *testing.T) {
-			actual, err := tc.b.Encode(tc.in)
+			actual, err := tc.b.Encode(tc.in, tc.options...)
 			if err != nil && !tc.withError {
 				t.Fatalf("Unexpected error: %v\n", err)
 				return
@@ -0,0 +1,96 @@
+package bpe
+
+import (
+	"unicode"
+)
+
+// Byte-level models work with bytes instead of characters.
+// Every byte is mapped to a printable rune the same way GPT-2 does it,
+// so any input is representable with the base alphabet of 256 symbols.
+var (
+	byteToRune [256]rune
+	runeToByte = make(map[rune]byte, 256)
+)
+
+func init() {
+	n := 0
+
+	for b := 0; b < 256; b++ {
+		r := rune(b)
+
+		// Printable Latin-1 bytes are mapped to themselves, others are shifted out of the way.
+		if !isPrintableByte(b) {
+			r = rune(256 + n)
+			n++
+		}
+
+		byteToRune[b] = r
+		runeToByte[r] = byte(b)
+	}
+}
+
+func isPrintableByte(b int) bool {
+	return (b >= '!' && b <= '~') || (b >= 0xA1 && b <= 0xAC) || (b >= 0xAE && b <= 0xFF)
+}
+
+// fromByteLevel restores bytes mapped to byte-level symbols.
+// Runes out of the byte-level alphabet are kept as is.
+func fromByteLevel(s string) []byte {
+	result := make([]byte, 0, len(s))
+
+	for _, r := range s {
+		if b, ok := runeToByte[r]; ok {
+			result = append(result, b)
+			continue
+		}
+
+		result = append(result, string(r)...)
+	}
+
+	return result
+}
+
+// byteLevelSymbols splits word into byte-level symbols.
+func byteLevelSymbols(word string) []string {
+	symbols := make([]string, 0, len(word))
+	for i := 0; i < len(word); i++ {
+		symbols = append(symbols, string(byteToRune[word[i]]))
+	}
+
+	return symbols
+}
+
+// byteLevelAlphabet returns all 256 byte-level symbols.
+func byteLevelAlphabet() []string {
+	alphabet := make([]string, 0, len(byteToRune))
+	for _, r := range byteToRune {
+		alphabet = append(alphabet, string(r))
+	}
+
+	return alphabet
+}
+
+// splitWithSpaces splits text into words keeping spaces as a prefix of the following word.
+// Concatenation of the words gives the original text.
+func splitWithSpaces(text string) []string {
+	var words []string
+	start := 0
+	inWord := false
+
+	for i, r := range text {
+		isSpace := unicode.IsSpace(r)
+
+		if isSpace && inWord {
+			words = append(words, text[start:i])
+			start = i
+		}
+
+		inWord = !isSpace
+	}
+
+	if start < len(text) {
+		words = append(words, text[start:])
+	}
+
+	return words
+}
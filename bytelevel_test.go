@@ -0,0 +1,123 @@
+package bpe
+
+import (
+	"bytes"
+	"context"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestByteLevelAlphabet(t *testing.T) {
+	alphabet := byteLevelAlphabet()
+	if len(alphabet) != 256 {
+		t.Fatalf("Expected 256 symbols. Got: %d", len(alphabet))
+	}
+
+	seen := make(map[string]struct{}, len(alphabet))
+
+	for b, symbol := range alphabet {
+		if _, ok := seen[symbol]; ok {
+			t.Errorf("Symbol %q is duplicated", symbol)
+		}
+
+		seen[symbol] = struct{}{}
+
+		if restored := fromByteLevel(symbol); !bytes.Equal(restored, []byte{byte(b)}) {
+			t.Errorf("Expected: %v\nGot: %v\n", []byte{byte(b)}, restored)
+		}
+	}
+
+	// The same mapping as GPT-2 uses.
+	if alphabet[' '] != "Ġ" || alphabet['\n'] != "Ċ" || alphabet['a'] != "a" {
+		t.Errorf("Unexpected mapping of space, new line or letter: %q %q %q", alphabet[' '], alphabet['\n'], alphabet['a'])
+	}
+}
+
+func TestSplitWithSpaces(t *testing.T) {
+	tt := []struct {
+		text     string
+		expected []string
+	}{
+		{text: "", expected: nil},
+		{text: "foo", expected: []string{"foo"}},
+		{text: "foo bar", expected: []string{"foo", " bar"}},
+		{text: "  foo \tbar  ", expected: []string{"  foo", " \tbar", "  "}},
+		{text: "\n", expected: []string{"\n"}},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.text, func(t *testing.T) {
+			actual := splitWithSpaces(tc.text)
+			if !reflect.DeepEqual(tc.expected, actual) {
+				t.Errorf("Expected: %q\nGot: %q\n", tc.expected, actual)
+			}
+		})
+	}
+}
+
+func TestTrain_ByteLevel(t *testing.T) {
+	corpus := "Lorem ipsum dolor sit amet.\nLorem ipsum dolor sit amet again."
+
+	model, err := Train(context.Background(), strings.NewReader(corpus), WithByteLevel(), WithMaxNumberOfTokens(300))
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	if len(model.vocab) <= 256 || len(model.vocab) > 300 {
+		t.Errorf("Expected vocab with byte alphabet and some merges.\nGot: %d tokens", len(model.vocab))
+	}
+
+	tt := []string{
+		corpus,
+		"  leading and trailing spaces \t\n",
+		"Unseen text: тест, 藍可兒, 🙂!",
+		"Broken UTF-8: \xff\xfe.",
+		"",
+	}
+
+	for _, text := range tt {
+		t.Run(text, func(t *testing.T) {
+			tokens, err := model.Encode(strings.NewReader(text))
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+
+			for _, token := range tokens {
+				if token == UnknownToken {
+					t.Fatalf("Unexpected unknown token in %q", tokens)
+				}
+			}
+
+			decoded, err := model.Decode(tokens)
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+
+			if decoded != text {
+				t.Errorf("Expected: %q\nGot: %q\n", text, decoded)
+			}
+		})
+	}
+}
+
+func TestTrain_ByteLevelExportImport(t *testing.T) {
+	model, err := Train(context.Background(), strings.NewReader("foo bar"), WithByteLevel())
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	buf := bytes.NewBuffer(nil)
+	if err := Export(model, buf); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	imported, err := Import(buf)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	if !reflect.DeepEqual(model, imported) {
+		t.Errorf("Expected: %v\nGot: %v\n", model, imported)
+	}
+}
@@ -0,0 +1,224 @@
+package bpe
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// CheckpointFileName is the name of checkpoint file written by WithCheckpointDir.
+const CheckpointFileName = "checkpoint.json"
+
+// checkpoint is the state of counting which is enough to continue training.
+type checkpoint struct {
+	BytesConsumed int64 `json:"bytes_consumed"` // Sentences before this offset are counted.
+	trainingStatistics
+}
+
+// WithCheckpoint makes training write checkpoints to w at most once per interval
+// and once more if training is cancelled. Every checkpoint is a separate JSON document,
+// ResumeTrain uses the last complete one.
+// Checkpoints are supported by Train and ResumeTrain only.
+func WithCheckpoint(w io.Writer, interval time.Duration) TrainOption {
+	return func(opts *trainOptions) {
+		opts.CheckpointInterval = interval
+		opts.CheckpointWrite = func(cp *checkpoint) error {
+			return json.NewEncoder(w).Encode(cp)
+		}
+	}
+}
+
+// WithCheckpointDir works like WithCheckpoint but keeps the only checkpoint in the CheckpointFileName file
+// of the directory. The file is replaced atomically, so it's never left half-written.
+func WithCheckpointDir(dir string, interval time.Duration) TrainOption {
+	return func(opts *trainOptions) {
+		opts.CheckpointInterval = interval
+		opts.CheckpointWrite = func(cp *checkpoint) error {
+			return writeCheckpointFile(dir, cp)
+		}
+	}
+}
+
+func writeCheckpointFile(dir string, cp *checkpoint) error {
+	f, err := ioutil.TempFile(dir, CheckpointFileName+".*")
+	if err != nil {
+		return err
+	}
+
+	defer os.Remove(f.Name()) // Fails after successful rename.
+
+	w := bufio.NewWriter(f)
+	if err := json.NewEncoder(w).Encode(cp); err != nil {
+		f.Close()
+		return err
+	}
+
+	if err := w.Flush(); err != nil {
+		f.Close()
+		return err
+	}
+
+	if err := f.Close(); err != nil {
+		return err
+	}
+
+	return os.Rename(f.Name(), filepath.Join(dir, CheckpointFileName))
+}
+
+// ResumeTrain continues training from the checkpoint. Source must be the same as the one given to Train.
+// Already counted part of the source is skipped. Options which affect counting are restored from the checkpoint
+// and can't be changed. Other options like WithMaxNumberOfTokens, WithWorkers or WithCheckpoint could be set again.
+func ResumeTrain(ctx context.Context, source io.Reader, checkpointSource io.Reader, opts ...TrainOption) (*BPE, error) {
+	cp, err := readCheckpoint(checkpointSource)
+	if err != nil {
+		return nil, err
+	}
+
+	options := defaultTrainOptions()
+	if err := cp.Options.Apply(options); err != nil {
+		return nil, err
+	}
+
+	options.Apply(opts...)
+
+	if !cp.Options.sameCounting(newSavedTrainOptions(options)) {
+		return nil, errors.New("counting options differ from the checkpoint")
+	}
+
+	if err := skipBytes(source, cp.BytesConsumed); err != nil {
+		return nil, errors.Wrap(err, "skip counted part of the source")
+	}
+
+	options.startProgress()
+	options.startCheckpoints(cp.Frequencies, cp.BytesConsumed)
+
+	tft, err := calculateTokensFrequency(ctx, source, options)
+	if err != nil {
+		return nil, err
+	}
+
+	tft = mergeTokensFrequencyTables([]tokensFrequencyTable{cp.Frequencies, tft})
+	pruneCandidates(tft, options.MaxCandidates)
+
+	return newModelFromFrequencyTable(tft, options), nil
+}
+
+// readCheckpoint returns the last complete checkpoint from r.
+// Incomplete tail is ignored because it's expected when training is killed during writing.
+func readCheckpoint(r io.Reader) (*checkpoint, error) {
+	decoder := json.NewDecoder(r)
+	var last *checkpoint
+
+	for {
+		cp := &checkpoint{}
+		if err := decoder.Decode(cp); err != nil {
+			if last != nil && (err == io.EOF || err == io.ErrUnexpectedEOF) {
+				return last, nil
+			}
+
+			return nil, errors.Wrap(err, "read checkpoint")
+		}
+
+		last = cp
+	}
+}
+
+func skipBytes(r io.Reader, n int64) error {
+	if seeker, ok := r.(io.Seeker); ok {
+		_, err := seeker.Seek(n, io.SeekCurrent)
+		return err
+	}
+
+	_, err := io.CopyN(ioutil.Discard, r, n)
+
+	return err
+}
+
+// startCheckpoints starts writing checkpoints if it's requested.
+// Base is the statistics counted before the current source offset.
+func (o *trainOptions) startCheckpoints(base tokensFrequencyTable, offset int64) {
+	if o.CheckpointWrite == nil {
+		return
+	}
+
+	o.checkpoint = &checkpointer{
+		write:    o.CheckpointWrite,
+		interval: o.CheckpointInterval,
+		lastSave: time.Now(),
+		base:     base,
+		offset:   offset,
+		options:  newSavedTrainOptions(o),
+	}
+}
+
+// checkpointer writes checkpoints of counting. Methods of nil checkpointer do nothing.
+type checkpointer struct {
+	write    func(cp *checkpoint) error
+	interval time.Duration
+	lastSave time.Time
+	base     tokensFrequencyTable
+	offset   int64
+	options  savedTrainOptions
+}
+
+// due checks whether it's time to write a checkpoint.
+func (c *checkpointer) due() bool {
+	return c != nil && time.Since(c.lastSave) >= c.interval
+}
+
+// save writes statistics of the source up to consumed bytes counted by given tables.
+// Tables aren't modified.
+func (c *checkpointer) save(consumed int64, tables ...tokensFrequencyTable) error {
+	if c == nil {
+		return nil
+	}
+
+	frequencies := make(tokensFrequencyTable, len(c.base))
+	for token, count := range c.base {
+		frequencies[token] = count
+	}
+
+	for _, tft := range tables {
+		for token, count := range tft {
+			frequencies[token] += count
+		}
+	}
+
+	c.lastSave = time.Now()
+
+	err := c.write(&checkpoint{
+		BytesConsumed: c.offset + consumed,
+		trainingStatistics: trainingStatistics{
+			Options:     c.options,
+			Frequencies: frequencies,
+		},
+	})
+
+	return errors.Wrap(err, "write checkpoint")
+}
+
+// cancel writes the last checkpoint of cancelled training and returns the cancellation error.
+func (c *checkpointer) cancel(ctx context.Context, consumed int64, tables ...tokensFrequencyTable) error {
+	if err := c.save(consumed, tables...); err != nil {
+		return err
+	}
+
+	return ctx.Err()
+}
+
+// countConsumed wraps split function to count bytes consumed by the scanner.
+func countConsumed(split bufio.SplitFunc, consumed *int64) bufio.SplitFunc {
+	return func(data []byte, atEOF bool) (int, []byte, error) {
+		advance, token, err := split(data, atEOF)
+		*consumed += int64(advance)
+
+		return advance, token, err
+	}
+}
@@ -0,0 +1,235 @@
+package bpe
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestResumeTrain(t *testing.T) {
+	example, err := ioutil.ReadFile("example.txt")
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	corpus := bytes.Repeat(example, 200)
+
+	tt := []struct {
+		name    string
+		options []TrainOption
+	}{
+		{
+			name:    "merges",
+			options: []TrainOption{WithMaxNumberOfTokens(300)},
+		},
+		{
+			name:    "merges with workers",
+			options: []TrainOption{WithMaxNumberOfTokens(300), WithWorkers(4)},
+		},
+		{
+			name:    "byte level",
+			options: []TrainOption{WithMaxNumberOfTokens(300), WithByteLevel()},
+		},
+		{
+			name:    "substring frequency",
+			options: []TrainOption{WithSubstringFrequency()},
+		},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			expected, err := Train(context.Background(), bytes.NewReader(corpus), tc.options...)
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+
+			// Training is cancelled in the middle and writes checkpoint on its way out.
+			ctx, cancel := context.WithCancel(context.Background())
+			defer cancel()
+
+			checkpoints := bytes.NewBuffer(nil)
+			source := &cancellingReader{r: bytes.NewReader(corpus), limit: len(corpus) / 2, cancel: cancel}
+
+			_, err = Train(ctx, source, append(tc.options, WithCheckpoint(checkpoints, time.Hour))...)
+			if err != context.Canceled {
+				t.Fatalf("Expected: %v\nGot: %v\n", context.Canceled, err)
+			}
+
+			cp, err := readCheckpoint(bytes.NewReader(checkpoints.Bytes()))
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+
+			if cp.BytesConsumed == 0 || cp.BytesConsumed >= int64(len(corpus)) || len(cp.Frequencies) == 0 {
+				t.Fatalf("Checkpoint expected in the middle of the source. Got offset: %v", cp.BytesConsumed)
+			}
+
+			// Options are restored from the checkpoint.
+			actual, err := ResumeTrain(context.Background(), bytes.NewReader(corpus), checkpoints)
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+
+			if !reflect.DeepEqual(expected.vocab, actual.vocab) {
+				t.Errorf("Expected: %v\nGot: %v\n", expected.vocab, actual.vocab)
+			}
+
+			// Tokens go in order of their IDs.
+			if !reflect.DeepEqual(expected.tokens, actual.tokens) {
+				t.Errorf("Expected tokens: %v\nGot: %v\n", expected.tokens, actual.tokens)
+			}
+		})
+	}
+}
+
+func TestResumeTrain_IncompleteCheckpoint(t *testing.T) {
+	corpus := "Low lower lowest. Newer newest! Wider widest? Low newer wider."
+
+	expected, err := Train(context.Background(), strings.NewReader(corpus))
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	checkpoints := bytes.NewBuffer(nil)
+
+	_, err = Train(context.Background(), strings.NewReader(corpus), WithCheckpoint(checkpoints, 0))
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	// Keep two complete checkpoints and a part of the third one.
+	documents := strings.SplitAfter(checkpoints.String(), "\n")
+	broken := documents[0] + documents[1] + documents[2][:len(documents[2])/2]
+
+	cp, err := readCheckpoint(strings.NewReader(broken))
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	if cp.BytesConsumed == 0 || cp.BytesConsumed >= int64(len(corpus)) {
+		t.Errorf("Checkpoint expected in the middle of the source. Got offset: %v", cp.BytesConsumed)
+	}
+
+	actual, err := ResumeTrain(context.Background(), strings.NewReader(corpus), strings.NewReader(broken))
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	if !reflect.DeepEqual(expected.tokens, actual.tokens) {
+		t.Errorf("Expected: %v\nGot: %v\n", expected.tokens, actual.tokens)
+	}
+}
+
+func TestResumeTrain_Errors(t *testing.T) {
+	checkpoints := bytes.NewBuffer(nil)
+
+	_, err := Train(context.Background(), strings.NewReader("Foo bar. Baz."), WithCheckpoint(checkpoints, 0))
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	tt := []struct {
+		name       string
+		source     string
+		checkpoint string
+		options    []TrainOption
+	}{
+		{
+			name:       "empty checkpoint",
+			source:     "Foo bar. Baz.",
+			checkpoint: "",
+		},
+		{
+			name:       "counting options differ",
+			source:     "Foo bar. Baz.",
+			checkpoint: checkpoints.String(),
+			options:    []TrainOption{WithByteLevel()},
+		},
+		{
+			name:       "source is too short",
+			source:     "Foo",
+			checkpoint: checkpoints.String(),
+		},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			_, err := ResumeTrain(context.Background(), &onlyReader{strings.NewReader(tc.source)}, strings.NewReader(tc.checkpoint), tc.options...)
+			if err == nil {
+				t.Error("Error expected")
+			}
+		})
+	}
+}
+
+func TestWithCheckpointDir(t *testing.T) {
+	dir := t.TempDir()
+	corpus := "Low lower lowest. Newer newest! Wider widest?"
+
+	_, err := Train(context.Background(), strings.NewReader(corpus), WithCheckpointDir(dir, 0))
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	files, err := filepath.Glob(filepath.Join(dir, "*"))
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	// Temporary files must be removed.
+	expectedFiles := []string{filepath.Join(dir, CheckpointFileName)}
+	if !reflect.DeepEqual(expectedFiles, files) {
+		t.Errorf("Expected: %v\nGot: %v\n", expectedFiles, files)
+	}
+
+	f, err := os.Open(filepath.Join(dir, CheckpointFileName))
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	defer f.Close()
+
+	cp, err := readCheckpoint(f)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	if cp.BytesConsumed != int64(len(corpus)) {
+		t.Errorf("Expected: %v\nGot: %v\n", len(corpus), cp.BytesConsumed)
+	}
+}
+
+// cancellingReader cancels context once limit bytes are read.
+type cancellingReader struct {
+	r      io.Reader
+	limit  int
+	read   int
+	cancel context.CancelFunc
+}
+
+func (r *cancellingReader) Read(p []byte) (int, error) {
+	n, err := r.r.Read(p)
+
+	r.read += n
+	if r.read >= r.limit {
+		r.cancel()
+	}
+
+	return n, err
+}
+
+// onlyReader hides other interfaces of the reader like io.Seeker.
+type onlyReader struct {
+	r io.Reader
+}
+
+func (r *onlyReader) Read(p []byte) (int, error) {
+	return r.r.Read(p)
+}
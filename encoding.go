@@ -0,0 +1,201 @@
+package bpe
+
+import (
+	"io"
+)
+
+// EncodedToken is a token with details about where it comes from.
+type EncodedToken struct {
+	Token string
+	ID    int
+	Span  Span
+
+	// Special is set for tokens which don't come from text like begin and end of sentence.
+	Special bool
+
+	// Word is index of the word which token comes from. It's -1 for special tokens.
+	// Added and reserved tokens are words by themselves.
+	Word int
+
+	// Sentence is index of the sentence which token belongs to. It's -1 for padding.
+	Sentence int
+
+	// Sequence is 0 for tokens of the first text and 1 for tokens of the second one of a pair.
+	// It's -1 for padding.
+	Sequence int
+
+	// Padding is set for tokens added to fill encoding up to the requested length.
+	Padding bool
+}
+
+// Encoding is the result of encoding with details about every token.
+type Encoding struct {
+	Tokens []EncodedToken
+
+	// Overflowing are windows with tokens which didn't fit into the maximum length.
+	// They are filled only if truncation is requested with WithMaxLength.
+	Overflowing []*Encoding
+}
+
+// EncodeDetailed works like Encode but returns details about every token.
+func (b *BPE) EncodeDetailed(r io.Reader, opts ...EncodeOption) (*Encoding, error) {
+	options := defaultEncodeOptions()
+	options.Apply(opts...)
+
+	encoding, err := b.encodeDetailed(r, options)
+	if err != nil {
+		return nil, err
+	}
+
+	return b.fit(encoding, options)
+}
+
+// encodeDetailed encodes text from r into encoding without truncation and padding.
+func (b *BPE) encodeDetailed(r io.Reader, options *encodeOptions) (*Encoding, error) {
+	result, err := b.encode(r, options, true)
+	if err != nil {
+		return nil, err
+	}
+
+	ids := b.tokensToIDs(result.tokens)
+	encoding := &Encoding{Tokens: make([]EncodedToken, 0, len(result.tokens))}
+
+	for i, token := range result.tokens {
+		encoding.Tokens = append(encoding.Tokens, EncodedToken{
+			Token:    token,
+			ID:       ids[i],
+			Span:     result.spans[i],
+			Special:  result.words[i] == noWord,
+			Word:     result.words[i],
+			Sentence: result.sentences[i],
+		})
+	}
+
+	return encoding, nil
+}
+
+// Len returns number of tokens.
+func (e *Encoding) Len() int {
+	return len(e.Tokens)
+}
+
+// Strings returns tokens.
+func (e *Encoding) Strings() []string {
+	result := make([]string, 0, len(e.Tokens))
+	for _, t := range e.Tokens {
+		result = append(result, t.Token)
+	}
+
+	return result
+}
+
+// IDs returns IDs of tokens.
+func (e *Encoding) IDs() []int {
+	return e.ints(func(t EncodedToken) int { return t.ID })
+}
+
+// Offsets returns spans of tokens.
+func (e *Encoding) Offsets() []Span {
+	result := make([]Span, 0, len(e.Tokens))
+	for _, t := range e.Tokens {
+		result = append(result, t.Span)
+	}
+
+	return result
+}
+
+// SpecialTokensMask returns 1 for special tokens and 0 for others.
+func (e *Encoding) SpecialTokensMask() []int {
+	return e.ints(func(t EncodedToken) int { return boolToInt(t.Special) })
+}
+
+// AttentionMask returns 1 for tokens which model should attend to and 0 for padding.
+func (e *Encoding) AttentionMask() []int {
+	return e.ints(func(t EncodedToken) int { return boolToInt(!t.Padding) })
+}
+
+// WordIDs returns word index of every token. It's -1 for special tokens.
+func (e *Encoding) WordIDs() []int {
+	return e.ints(func(t EncodedToken) int { return t.Word })
+}
+
+// SentenceIDs returns sentence index of every token. It's -1 for padding.
+func (e *Encoding) SentenceIDs() []int {
+	return e.ints(func(t EncodedToken) int { return t.Sentence })
+}
+
+// SequenceIDs returns index of the text of a pair which every token comes from. It's -1 for padding.
+func (e *Encoding) SequenceIDs() []int {
+	return e.ints(func(t EncodedToken) int { return t.Sequence })
+}
+
+func (e *Encoding) ints(value func(t EncodedToken) int) []int {
+	result := make([]int, 0, len(e.Tokens))
+	for _, t := range e.Tokens {
+		result = append(result, value(t))
+	}
+
+	return result
+}
+
+func boolToInt(b bool) int {
+	if b {
+		return 1
+	}
+
+	return 0
+}
+
+// encoded collects tokens. Details are collected only if they are requested.
+// While sentence is being encoded spans keep byte offsets in the text which is being encoded.
+type encoded struct {
+	tokens    []string
+	spans     []Span
+	words     []int
+	sentences []int
+	detailed  bool
+	offset    int // Offset of the current word. It's added to spans of its tokens.
+	word      int // Index of the current word.
+	wordCount int // Number of started words.
+	sentence  int // Index of the current sentence.
+}
+
+const (
+	noSpan = -1 // Marks span of tokens which don't come from text.
+	noWord = -1 // Marks word of tokens which don't come from text.
+)
+
+func (e *encoded) append(token string, start, end int) {
+	e.tokens = append(e.tokens, token)
+
+	if e.detailed {
+		e.spans = append(e.spans, Span{Start: e.offset + start, End: e.offset + end})
+		e.words = append(e.words, e.word)
+		e.sentences = append(e.sentences, e.sentence)
+	}
+}
+
+// appendSpecial appends token which doesn't come from text.
+func (e *encoded) appendSpecial(token string) {
+	e.tokens = append(e.tokens, token)
+
+	if e.detailed {
+		e.spans = append(e.spans, Span{Start: noSpan, End: noSpan})
+		e.words = append(e.words, noWord)
+		e.sentences = append(e.sentences, e.sentence)
+	}
+}
+
+// extend extends span of the last token up to the end.
+func (e *encoded) extend(end int) {
+	if e.detailed && len(e.spans) > 0 {
+		e.spans[len(e.spans)-1].End = e.offset + end
+	}
+}
+
+// startWord makes the following tokens belong to the next word starting at the offset.
+func (e *encoded) startWord(offset int) {
+	e.word = e.wordCount
+	e.wordCount++
+	e.offset = offset
+}
@@ -0,0 +1,78 @@
+package bpe
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestBPE_EncodeDetailed(t *testing.T) {
+	model := newModel([]string{BeginOfWord + "hi" + EndOfWord, BeginOfWord + "the", "re" + EndOfWord, "." + EndOfWord})
+	model.AddTokens(AddedToken{Content: "[URL]"})
+	model.special.Reserved = []string{"[MASK]"}
+
+	text := "hi there. [URL]hi [MASK]"
+
+	encoding, err := model.EncodeDetailed(strings.NewReader(text))
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	tokens, err := model.Encode(strings.NewReader(text))
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	ids, err := model.EncodeIDs(strings.NewReader(text))
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	_, offsets, err := model.EncodeWithOffsets(strings.NewReader(text))
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	tt := []struct {
+		name     string
+		actual   interface{}
+		expected interface{}
+	}{
+		{name: "strings", actual: encoding.Strings(), expected: tokens},
+		{name: "ids", actual: encoding.IDs(), expected: ids},
+		{name: "offsets", actual: encoding.Offsets(), expected: offsets},
+		{
+			// <s> <w>hi</w> <w>the <u> <u> .</w> </s> <s> [URL] <w>hi</w> [MASK] </s>
+			name:     "special tokens mask",
+			actual:   encoding.SpecialTokensMask(),
+			expected: []int{1, 0, 0, 0, 0, 0, 1, 1, 0, 0, 0, 1},
+		},
+		{
+			name:     "attention mask",
+			actual:   encoding.AttentionMask(),
+			expected: []int{1, 1, 1, 1, 1, 1, 1, 1, 1, 1, 1, 1},
+		},
+		{
+			name:     "word ids",
+			actual:   encoding.WordIDs(),
+			expected: []int{-1, 0, 1, 1, 1, 1, -1, -1, 2, 3, 4, -1},
+		},
+		{
+			name:     "sentence ids",
+			actual:   encoding.SentenceIDs(),
+			expected: []int{0, 0, 0, 0, 0, 0, 0, 1, 1, 1, 1, 1},
+		},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			if !reflect.DeepEqual(tc.expected, tc.actual) {
+				t.Errorf("Expected: %v\nGot: %v\n", tc.expected, tc.actual)
+			}
+		})
+	}
+
+	if encoding.Len() != len(tokens) {
+		t.Errorf("Expected length %d. Got: %d", len(tokens), encoding.Len())
+	}
+}
@@ -27,7 +27,7 @@ func ExampleTrain() {
 	// Check Export() function.
 
 	fmt.Printf("%d", len(model.vocab))
-	// Output: 29
+	// Output: 17
 }
 
 func ExampleExport() {
@@ -11,11 +11,28 @@ func Export(model *BPE, w io.Writer, opts ...ExportOption) error {
 
 	m := exportedModel{
 		MaxTokenLength: model.maxTokenLength,
-		Vocab:          make([]string, 0, len(model.vocab)),
+		Vocab:          model.orderedVocab(),
+		ByteLevel:      model.byteLevel,
+		Statistics:     model.statistics,
+		Normalizers:    normalizerNames(model.normalizers),
+		PreTokenizers:  preTokenizerNames(model.preTokenizers),
+		Splitter:       sentenceSplitterName(model.splitter),
 	}
 
-	for t := range model.vocab {
-		m.Vocab = append(m.Vocab, t)
+	for _, token := range model.orderedAddedTokens() {
+		m.AddedTokens = append(m.AddedTokens, exportedAddedToken{
+			AddedToken: token,
+			ID:         model.ids[token.Content],
+		})
+	}
+
+	// Default special tokens are omitted to keep exported model compact.
+	if special := model.specialTokens(); !special.isDefault() {
+		m.SpecialTokens = &special
+	}
+
+	for _, pair := range model.orderedMerges() {
+		m.Merges = append(m.Merges, [2]string{pair.Left, pair.Right})
 	}
 
 	return options.Encoder.Encode(w, m)
@@ -50,8 +67,22 @@ func WithEncoder(enc ModelEncoder) ExportOption {
 }
 
 type exportedModel struct {
-	MaxTokenLength int      `json:"max_token_length"`
-	Vocab          []string `json:"vocab"`
+	MaxTokenLength int                  `json:"max_token_length"`
+	Vocab          []string             `json:"vocab"`            // Ordered by ID.
+	Merges         [][2]string          `json:"merges,omitempty"` // Ordered by rank.
+	ByteLevel      bool                 `json:"byte_level,omitempty"`
+	SpecialTokens  *SpecialTokens       `json:"special_tokens,omitempty"`
+	AddedTokens    []exportedAddedToken `json:"added_tokens,omitempty"` // Ordered by ID.
+	Statistics     *trainingStatistics  `json:"statistics,omitempty"`
+	Normalizers    []string             `json:"normalizers,omitempty"`       // Names of registered normalizers.
+	PreTokenizers  []string             `json:"pre_tokenizers,omitempty"`    // Names of registered pre-tokenizers.
+	Splitter       string               `json:"sentence_splitter,omitempty"` // Name of registered sentence splitter.
+}
+
+// exportedAddedToken keeps ID of added token because vocabulary could be extended after tokens were added.
+type exportedAddedToken struct {
+	AddedToken
+	ID int `json:"id,omitempty"` // Tokens without ID get IDs right after the vocabulary.
 }
 
 type defaultEncoder struct{}
@@ -37,6 +37,20 @@ func TestExport(t *testing.T) {
 			),
 			expected: `{"max_token_length":3,"vocab":["foo"]}` + "\n",
 		},
+		{
+			name: "merges",
+			model: &BPE{
+				maxTokenLength: 3,
+				vocab: map[string]struct{}{
+					"ab": {},
+				},
+				merges: map[mergePair]int{
+					{Left: "b", Right: "c"}: 1,
+					{Left: "a", Right: "b"}: 0,
+				},
+			},
+			expected: `{"max_token_length":3,"vocab":["ab"],"merges":[["a","b"],["b","c"]]}` + "\n",
+		},
 		{
 			name:  "mocked encoder",
 			model: &BPE{},
@@ -0,0 +1,58 @@
+package bpe
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// Byte tokens represent characters out of vocabulary when byte fallback is enabled.
+// They are always part of the vocabulary and have fixed IDs right after the special tokens.
+var byteTokens = func() []string {
+	tokens := make([]string, 256)
+	for b := range tokens {
+		tokens[b] = fmt.Sprintf("<0x%02X>", b)
+	}
+
+	return tokens
+}()
+
+// parseByteToken returns byte represented by the token. The second value is false if it's not a byte token.
+func parseByteToken(token string) (byte, bool) {
+	if len(token) != len("<0x00>") || !strings.HasPrefix(token, "<0x") || token[len(token)-1] != '>' {
+		return 0, false
+	}
+
+	b, err := strconv.ParseUint(token[3:5], 16, 8)
+	if err != nil || byteTokens[b] != token {
+		return 0, false
+	}
+
+	return byte(b), true
+}
+
+// appendByteFallback appends byte tokens of the unit. Word boundary markers are kept as separate tokens.
+// Start and end are span of the unit. Every byte token covers its byte if the unit comes from text as is,
+// otherwise byte tokens cover the whole span.
+func appendByteFallback(target *encoded, unit string, special SpecialTokens, start, end int) {
+	if strings.HasPrefix(unit, special.BeginOfWord) {
+		target.append(special.BeginOfWord, start, start)
+		unit = unit[len(special.BeginOfWord):]
+	}
+
+	endOfWord := strings.HasSuffix(unit, special.EndOfWord)
+	unit = strings.TrimSuffix(unit, special.EndOfWord)
+	exact := len(unit) == end-start
+
+	for i := 0; i < len(unit); i++ {
+		if exact {
+			target.append(byteTokens[unit[i]], start+i, start+i+1)
+		} else {
+			target.append(byteTokens[unit[i]], start, end)
+		}
+	}
+
+	if endOfWord {
+		target.append(special.EndOfWord, end, end)
+	}
+}
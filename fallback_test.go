@@ -0,0 +1,117 @@
+package bpe
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestParseByteToken(t *testing.T) {
+	tt := []struct {
+		token      string
+		expected   byte
+		expectedOk bool
+	}{
+		{token: "<0x00>", expected: 0x00, expectedOk: true},
+		{token: "<0xE2>", expected: 0xE2, expectedOk: true},
+		{token: "<0xe2>"},
+		{token: "<0xE2"},
+		{token: "<0xZZ>"},
+		{token: "<0x-1>"},
+		{token: "<w>"},
+		{token: ""},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.token, func(t *testing.T) {
+			actual, ok := parseByteToken(tc.token)
+			if actual != tc.expected || ok != tc.expectedOk {
+				t.Errorf("Expected: %v %v\nGot: %v %v\n", tc.expected, tc.expectedOk, actual, ok)
+			}
+		})
+	}
+}
+
+func TestBPE_Encode_ByteFallback(t *testing.T) {
+	tt := []struct {
+		name     string
+		b        *BPE
+		in       string
+		expected []string
+		decoded  string
+	}{
+		{
+			name: "greedy",
+			b:    newModel([]string{BeginOfWord + "f", "o", "o" + EndOfWord, BeginOfWord}),
+			in:   "foé ё",
+			expected: []string{
+				BeginOfSentence,
+				BeginOfWord + "f", "o", "<0xC3>", "<0xA9>", EndOfWord,
+				BeginOfWord, "<0xD1>", "<0x91>", EndOfWord,
+				EndOfSentence,
+			},
+			decoded: "foé ё",
+		},
+		{
+			name: "merges",
+			b: func() *BPE {
+				model := newModel([]string{BeginOfWord + "f", "o", "o" + EndOfWord, BeginOfWord + "fo"})
+				model.merges = map[mergePair]int{
+					{Left: BeginOfWord + "f", Right: "o"}: 0,
+				}
+
+				return model
+			}(),
+			in: "foé 🙂",
+			expected: []string{
+				BeginOfSentence,
+				BeginOfWord + "fo", "<0xC3>", "<0xA9>", EndOfWord,
+				BeginOfWord, "<0xF0>", "<0x9F>", "<0x99>", "<0x82>", EndOfWord,
+				EndOfSentence,
+			},
+			decoded: "foé 🙂",
+		},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			tokens, err := tc.b.Encode(strings.NewReader(tc.in), WithByteFallback())
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+
+			if !reflect.DeepEqual(tc.expected, tokens) {
+				t.Errorf("Expected: %v\nGot: %v\n", tc.expected, tokens)
+			}
+
+			decoded, err := tc.b.Decode(tokens)
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+
+			if decoded != tc.decoded {
+				t.Errorf("Expected: %v\nGot: %v\n", tc.decoded, decoded)
+			}
+
+			ids, err := tc.b.EncodeIDs(strings.NewReader(tc.in), WithByteFallback())
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+
+			for _, id := range ids {
+				if id == 0 {
+					t.Fatalf("Unexpected unknown token id in %v", ids)
+				}
+			}
+
+			decoded, err = tc.b.DecodeIDs(ids)
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+
+			if decoded != tc.decoded {
+				t.Errorf("Expected: %v\nGot: %v\n", tc.decoded, decoded)
+			}
+		})
+	}
+}
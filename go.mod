@@ -1,5 +1,8 @@
 module github.com/cheshir/bpe
 
-go 1.15
+go 1.17
 
-require github.com/pkg/errors v0.9.1
+require (
+	github.com/pkg/errors v0.9.1
+	golang.org/x/text v0.13.0
+)
@@ -0,0 +1,118 @@
+package bpe
+
+import (
+	"io"
+	"sort"
+
+	"github.com/pkg/errors"
+)
+
+// assignIDs gives IDs to the reserved tokens and then to the given tokens in their order.
+// Special tokens go first so they have fixed low IDs, then byte tokens.
+// Vocabulary tokens get IDs right after them in order of rank or frequency.
+func (b *BPE) assignIDs(tokens []string) {
+	reservedTokens := b.specialTokens().reservedTokens()
+	b.tokens = make([]string, 0, len(reservedTokens)+len(tokens))
+	b.ids = make(map[string]int, len(reservedTokens)+len(tokens))
+
+	for _, token := range reservedTokens {
+		b.addID(token)
+	}
+
+	for _, token := range tokens {
+		b.addID(token)
+	}
+}
+
+func (b *BPE) addID(token string) {
+	if _, ok := b.ids[token]; ok {
+		return
+	}
+
+	b.ids[token] = len(b.tokens)
+	b.tokens = append(b.tokens, token)
+}
+
+// orderedVocab returns vocabulary without reserved and added tokens ordered by ID.
+func (b *BPE) orderedVocab() []string {
+	if len(b.tokens) == 0 {
+		// Model has been created without IDs.
+		vocab := make([]string, 0, len(b.vocab))
+		for token := range b.vocab {
+			vocab = append(vocab, token)
+		}
+
+		sort.Strings(vocab)
+
+		return vocab
+	}
+
+	vocab := make([]string, 0, len(b.vocab))
+
+	for _, token := range b.tokens[len(b.specialTokens().reservedTokens()):] {
+		if _, ok := b.vocab[token]; ok {
+			vocab = append(vocab, token)
+		}
+	}
+
+	return vocab
+}
+
+// TokenToID returns ID of the token. The second value is false if token is out of vocabulary.
+func (b *BPE) TokenToID(token string) (int, bool) {
+	id, ok := b.ids[token]
+
+	return id, ok
+}
+
+// IDToToken returns token by its ID. The second value is false if there is no token with such ID.
+func (b *BPE) IDToToken(id int) (string, bool) {
+	if id >= 0 && id < len(b.tokens) {
+		return b.tokens[id], true
+	}
+
+	return "", false
+}
+
+// EncodeIDs works like Encode but returns IDs of tokens.
+func (b *BPE) EncodeIDs(r io.Reader, opts ...EncodeOption) ([]int, error) {
+	tokens, err := b.Encode(r, opts...)
+	if err != nil {
+		return nil, err
+	}
+
+	return b.tokensToIDs(tokens), nil
+}
+
+// tokensToIDs returns IDs of tokens. Tokens out of vocabulary get ID of unknown token.
+func (b *BPE) tokensToIDs(tokens []string) []int {
+	unknownID, _ := b.TokenToID(b.specialTokens().Unknown)
+	ids := make([]int, 0, len(tokens))
+
+	for _, token := range tokens {
+		id, ok := b.TokenToID(token)
+		if !ok {
+			id = unknownID
+		}
+
+		ids = append(ids, id)
+	}
+
+	return ids
+}
+
+// DecodeIDs converts IDs to tokens and decodes them.
+func (b *BPE) DecodeIDs(ids []int) (string, error) {
+	tokens := make([]string, 0, len(ids))
+
+	for _, id := range ids {
+		token, ok := b.IDToToken(id)
+		if !ok {
+			return "", errors.Errorf("unknown token id %d", id)
+		}
+
+		tokens = append(tokens, token)
+	}
+
+	return b.Decode(tokens)
+}
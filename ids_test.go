@@ -0,0 +1,161 @@
+package bpe
+
+import (
+	"bytes"
+	"context"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestBPE_TokenToID(t *testing.T) {
+	model := newModel([]string{"foo", "bar"})
+	vocabStart := len(DefaultSpecialTokens().reservedTokens())
+
+	tt := []struct {
+		token      string
+		expectedID int
+		expectedOk bool
+	}{
+		{token: UnknownToken, expectedID: 0, expectedOk: true},
+		{token: BeginOfSentence, expectedID: 1, expectedOk: true},
+		{token: EndOfSentence, expectedID: 2, expectedOk: true},
+		{token: BeginOfWord, expectedID: 3, expectedOk: true},
+		{token: EndOfWord, expectedID: 4, expectedOk: true},
+		{token: "<0x00>", expectedID: 5, expectedOk: true},
+		{token: "<0xFF>", expectedID: 260, expectedOk: true},
+		{token: "foo", expectedID: vocabStart, expectedOk: true},
+		{token: "bar", expectedID: vocabStart + 1, expectedOk: true},
+		{token: "baz", expectedID: 0, expectedOk: false},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.token, func(t *testing.T) {
+			id, ok := model.TokenToID(tc.token)
+			if id != tc.expectedID || ok != tc.expectedOk {
+				t.Errorf("Expected: %v %v\nGot: %v %v\n", tc.expectedID, tc.expectedOk, id, ok)
+			}
+
+			if !ok {
+				return
+			}
+
+			token, ok := model.IDToToken(id)
+			if token != tc.token || !ok {
+				t.Errorf("Expected: %v\nGot: %v %v\n", tc.token, token, ok)
+			}
+		})
+	}
+}
+
+func TestBPE_IDToToken(t *testing.T) {
+	tt := []struct {
+		name          string
+		model         *BPE
+		id            int
+		expectedToken string
+		expectedOk    bool
+	}{
+		{
+			name:          "vocab token",
+			model:         newModel([]string{"foo"}),
+			id:            len(DefaultSpecialTokens().reservedTokens()),
+			expectedToken: "foo",
+			expectedOk:    true,
+		},
+		{
+			name:  "out of range",
+			model: newModel([]string{"foo"}),
+			id:    len(DefaultSpecialTokens().reservedTokens()) + 1,
+		},
+		{
+			name:  "negative",
+			model: newModel([]string{"foo"}),
+			id:    -1,
+		},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			token, ok := tc.model.IDToToken(tc.id)
+			if token != tc.expectedToken || ok != tc.expectedOk {
+				t.Errorf("Expected: %v %v\nGot: %v %v\n", tc.expectedToken, tc.expectedOk, token, ok)
+			}
+		})
+	}
+}
+
+func TestBPE_TokenToID_NoAllocations(t *testing.T) {
+	model := newModel([]string{"foo"})
+
+	allocs := testing.AllocsPerRun(100, func() {
+		model.TokenToID(BeginOfSentence)
+		model.TokenToID("missing")
+		model.IDToToken(1)
+	})
+
+	if allocs != 0 {
+		t.Errorf("Expected no allocations. Got: %v", allocs)
+	}
+}
+
+func TestBPE_EncodeIDs(t *testing.T) {
+	model := newModel([]string{BeginOfWord + "foo" + EndOfWord, BeginOfWord + "ba", "r" + EndOfWord})
+
+	ids, err := model.EncodeIDs(strings.NewReader("foo bar baz"))
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	vocabStart := len(DefaultSpecialTokens().reservedTokens())
+
+	// <s> <w>foo</w> <w>ba r</w> <w>ba <u> </s>
+	expected := []int{1, vocabStart, vocabStart + 1, vocabStart + 2, vocabStart + 1, 0, 2}
+	if !reflect.DeepEqual(expected, ids) {
+		t.Errorf("Expected: %v\nGot: %v\n", expected, ids)
+	}
+
+	text, err := model.DecodeIDs(ids[:4])
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	if text != "foo bar" {
+		t.Errorf("Expected: %v\nGot: %v\n", "foo bar", text)
+	}
+}
+
+func TestBPE_DecodeIDs_UnknownID(t *testing.T) {
+	model := newModel([]string{"foo"})
+
+	if _, err := model.DecodeIDs([]int{1, 1000, 2}); err == nil {
+		t.Error("Error expected")
+	}
+}
+
+func TestIDs_ExportImport(t *testing.T) {
+	model, err := Train(context.Background(), strings.NewReader("low lower lowest newer newest wider"))
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	buf := bytes.NewBuffer(nil)
+	if err := Export(model, buf); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	imported, err := Import(buf)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	if !reflect.DeepEqual(model.tokens, imported.tokens) {
+		t.Errorf("Expected: %v\nGot: %v\n", model.tokens, imported.tokens)
+	}
+
+	for id, token := range model.tokens {
+		if actual, _ := imported.TokenToID(token); actual != id {
+			t.Errorf("Token %q expected to have ID %d. Got: %d", token, id, actual)
+		}
+	}
+}
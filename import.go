@@ -3,6 +3,8 @@ package bpe
 import (
 	"encoding/json"
 	"io"
+
+	"github.com/pkg/errors"
 )
 
 func Import(r io.Reader, opts ...ImportOption) (*BPE, error) {
@@ -49,16 +51,84 @@ func (e *defaultDecoder) Decode(r io.Reader) (*BPE, error) {
 		return nil, err
 	}
 
-	vocab := make(map[string]struct{}, len(dto.Vocab))
+	merges := make([]mergePair, 0, len(dto.Merges))
+
+	for _, pair := range dto.Merges {
+		merges = append(merges, mergePair{Left: pair[0], Right: pair[1]})
+	}
+
+	special := DefaultSpecialTokens()
+	if dto.SpecialTokens != nil {
+		special = *dto.SpecialTokens
+	}
+
+	normalizers, err := lookupNormalizers(dto.Normalizers)
+	if err != nil {
+		return nil, err
+	}
 
-	for _, token := range dto.Vocab {
-		vocab[token] = struct{}{}
+	preTokenizers, err := lookupPreTokenizers(dto.PreTokenizers)
+	if err != nil {
+		return nil, err
 	}
 
-	model := &BPE{
-		maxTokenLength: dto.MaxTokenLength,
-		vocab:          vocab,
+	splitter, err := lookupSentenceSplitter(dto.Splitter)
+	if err != nil {
+		return nil, err
+	}
+
+	model := newModelWithSpecialTokens(dto.Vocab, special)
+	model.splitter = splitter
+	model.normalizers = normalizers
+	model.preTokenizers = preTokenizers
+	model.maxTokenLength = dto.MaxTokenLength
+	model.merges = newMergesTable(merges)
+	model.byteLevel = dto.ByteLevel
+	model.statistics = dto.Statistics
+
+	if err := model.importAddedTokens(dto.AddedTokens); err != nil {
+		return nil, err
 	}
 
 	return model, nil
 }
+
+// importAddedTokens restores added tokens with their IDs. Vocabulary tokens fill IDs between them in order.
+func (b *BPE) importAddedTokens(exported []exportedAddedToken) error {
+	tokens := make([]AddedToken, 0, len(exported))
+	for _, token := range exported {
+		tokens = append(tokens, token.AddedToken)
+	}
+
+	if len(exported) == 0 || exported[0].ID == 0 {
+		b.AddTokens(tokens...)
+		return nil
+	}
+
+	vocab := b.orderedVocab()
+	offset := len(b.specialTokens().reservedTokens())
+	ordered := make([]string, len(vocab)+len(exported))
+
+	for _, token := range exported {
+		position := token.ID - offset
+		if position < 0 || position >= len(ordered) || ordered[position] != "" {
+			return errors.Errorf("invalid id %d of added token %q", token.ID, token.Content)
+		}
+
+		ordered[position] = token.Content
+	}
+
+	next := 0
+
+	for i := range ordered {
+		if ordered[i] == "" {
+			ordered[i] = vocab[next]
+			next++
+		}
+	}
+
+	b.assignIDs(ordered)
+	b.AddTokens(tokens...)
+
+	return nil
+}
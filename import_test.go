@@ -36,6 +36,19 @@ func TestImport(t *testing.T) {
 				1,
 			),
 		},
+		{
+			name:   "merges",
+			source: strings.NewReader(`{"max_token_length":2,"vocab":["ab"],"merges":[["a","b"],["b","c"]]}`),
+			expected: func() *BPE {
+				model := newModel([]string{"ab"})
+				model.merges = map[mergePair]int{
+					{Left: "a", Right: "b"}: 0,
+					{Left: "b", Right: "c"}: 1,
+				}
+
+				return model
+			}(),
+		},
 		{
 			name:   "mocked decoder",
 			source: strings.NewReader(""),
@@ -96,3 +109,10 @@ func TestImport(t *testing.T) {
 		})
 	}
 }
+
+func TestImport_InvalidAddedTokenID(t *testing.T) {
+	_, err := Import(strings.NewReader(`{"max_token_length":3,"vocab":["foo"],"added_tokens":[{"content":"[TAG]","id":1000}]}`))
+	if err == nil {
+		t.Error("Error expected")
+	}
+}
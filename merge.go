@@ -0,0 +1,404 @@
+package bpe
+
+import (
+	"container/heap"
+	"sort"
+	"unicode/utf8"
+)
+
+// mergePair is a pair of adjacent symbols which could be merged into the single one.
+type mergePair struct {
+	Left  string
+	Right string
+}
+
+// mergeWord is a word from the corpus split into symbols.
+type mergeWord struct {
+	symbols []string
+	count   int
+}
+
+// newModelFromWordsFrequencyTable learns vocabulary the way byte pair encoding does.
+// It starts from characters and merges the most frequent pair of adjacent symbols
+// until the vocabulary reaches tokensLimit or there is nothing left to merge.
+func newModelFromWordsFrequencyTable(wft tokensFrequencyTable, options *trainOptions) *BPE {
+	vocab, merges := learnMerges(wft, options)
+	model := newModelWithSpecialTokens(vocab, options.SpecialTokens)
+	model.merges = newMergesTable(merges)
+	model.byteLevel = options.ByteLevel
+
+	return model
+}
+
+// newMergesTable converts ordered list of merges to the table of ranks.
+func newMergesTable(merges []mergePair) map[mergePair]int {
+	if len(merges) == 0 {
+		return nil
+	}
+
+	table := make(map[mergePair]int, len(merges))
+
+	for rank, pair := range merges {
+		// Keep the first rank if the same pair has been listed twice.
+		if _, ok := table[pair]; !ok {
+			table[pair] = rank
+		}
+	}
+
+	return table
+}
+
+// orderedMerges returns merges ordered by their rank.
+func (b *BPE) orderedMerges() []mergePair {
+	merges := make([]mergePair, 0, len(b.merges))
+	for pair := range b.merges {
+		merges = append(merges, pair)
+	}
+
+	sort.Slice(merges, func(i, j int) bool {
+		return b.merges[merges[i]] < b.merges[merges[j]]
+	})
+
+	return merges
+}
+
+// applyMerges merges adjacent symbols the same way reference implementations do:
+// while there is a known pair, the one with the lowest rank is merged everywhere in the word.
+func (b *BPE) applyMerges(symbols []string) []string {
+	for len(symbols) > 1 {
+		bestRank := -1
+		var best mergePair
+
+		for i := 0; i+1 < len(symbols); i++ {
+			pair := mergePair{Left: symbols[i], Right: symbols[i+1]}
+
+			rank, ok := b.merges[pair]
+			if ok && (bestRank == -1 || rank < bestRank) {
+				bestRank = rank
+				best = pair
+			}
+		}
+
+		if bestRank == -1 {
+			break
+		}
+
+		symbols = applyMerge(symbols, best, best.Left+best.Right)
+	}
+
+	return symbols
+}
+
+// learnMerges returns the vocabulary ordered by its creation: characters first, then merged symbols.
+// The second value is the ordered list of merges. Merge index is its rank.
+// Alphabet is always included in full even if it exceeds the limit.
+func learnMerges(wft tokensFrequencyTable, options *trainOptions) ([]string, []mergePair) {
+	tokensLimit := options.MaxNumberOfTokens
+	words, alphabetFrequency := splitWords(wft, options)
+
+	alphabet := selectAlphabet(alphabetFrequency, options)
+	if options.ByteLevel {
+		alphabet = byteLevelAlphabet()
+	}
+
+	if len(alphabet) > tokensLimit {
+		tokensLimit = len(alphabet)
+	}
+
+	vocab := make([]string, 0, tokensLimit)
+	vocab = append(vocab, alphabet...)
+
+	return mergeSymbols(words, vocab, nil, tokensLimit, options)
+}
+
+// continueMerges learns merges on top of the existing ones. Existing tokens and merges keep their order,
+// new symbols of the alphabet go before new merged symbols.
+func (b *BPE) continueMerges(wft tokensFrequencyTable, options *trainOptions) ([]string, []mergePair) {
+	words, alphabetFrequency := splitWords(wft, options)
+	vocab := b.orderedVocab()
+	merges := b.orderedMerges()
+
+	for _, symbol := range selectAlphabet(alphabetFrequency, options) {
+		if _, ok := b.vocab[symbol]; !ok && !options.ByteLevel {
+			vocab = append(vocab, symbol)
+		}
+	}
+
+	tokensLimit := options.MaxNumberOfTokens
+	if len(vocab) > tokensLimit {
+		tokensLimit = len(vocab)
+	}
+
+	for i := range words {
+		words[i].symbols = b.applyMerges(words[i].symbols)
+	}
+
+	return mergeSymbols(words, vocab, merges, tokensLimit, options)
+}
+
+// splitWords splits words into initial symbols. Words are sorted to make the result
+// independent of the map iteration order. The second value is the frequency of symbols.
+func splitWords(wft tokensFrequencyTable, options *trainOptions) ([]mergeWord, tokensFrequencyTable) {
+	keys := make([]string, 0, len(wft))
+	for word := range wft {
+		keys = append(keys, word)
+	}
+
+	sort.Strings(keys)
+
+	words := make([]mergeWord, 0, len(keys))
+	alphabetFrequency := make(tokensFrequencyTable)
+
+	for _, word := range keys {
+		symbols := splitSymbols(word, options.ByteLevel, options.SpecialTokens)
+		count := wft[word]
+
+		for _, s := range symbols {
+			alphabetFrequency[s] += count
+		}
+
+		words = append(words, mergeWord{
+			symbols: symbols,
+			count:   count,
+		})
+	}
+
+	return words, alphabetFrequency
+}
+
+// mergeSymbols merges the most frequent pair of adjacent symbols of words
+// until the vocabulary reaches tokensLimit or there is nothing left to merge.
+// Symbols of words must be already merged with given merges. It returns extended vocabulary and merges.
+func mergeSymbols(
+	words []mergeWord,
+	vocab []string,
+	merges []mergePair,
+	tokensLimit int,
+	options *trainOptions,
+) ([]string, []mergePair) {
+	known := make(map[string]struct{}, tokensLimit)
+	symbolLength := make(map[string]int, tokensLimit)
+
+	for _, s := range vocab {
+		known[s] = struct{}{}
+		symbolLength[s] = 1
+	}
+
+	for _, pair := range merges {
+		symbolLength[pair.Left+pair.Right] = symbolLength[pair.Left] + symbolLength[pair.Right]
+	}
+
+	stats := newPairStats(options.MaxTokenLength, symbolLength)
+	for i, w := range words {
+		stats.addWord(i, w)
+	}
+
+	options.progress.setPhase(PhaseMerging)
+
+	for len(vocab) < tokensLimit {
+		pair, ok := stats.best()
+		if !ok || stats.counts[pair] < options.MinFrequency {
+			break
+		}
+
+		merged := pair.Left + pair.Right
+		symbolLength[merged] = symbolLength[pair.Left] + symbolLength[pair.Right]
+		merges = append(merges, pair)
+		options.progress.setMerges(len(merges))
+
+		if _, ok := known[merged]; !ok {
+			known[merged] = struct{}{}
+			vocab = append(vocab, merged)
+		}
+
+		for _, i := range stats.wordsWith(pair) {
+			stats.removeWord(i, words[i])
+			words[i].symbols = applyMerge(words[i].symbols, pair, merged)
+			stats.addWord(i, words[i])
+		}
+	}
+
+	return vocab, merges
+}
+
+// splitSymbols splits word into initial symbols of byte-level or character-level model.
+func splitSymbols(word string, byteLevel bool, special SpecialTokens) []string {
+	if byteLevel {
+		return byteLevelSymbols(word)
+	}
+
+	return wordSymbols(word, special)
+}
+
+// wordSymbols splits word into characters and glues word boundary markers to the first and the last ones.
+func wordSymbols(word string, special SpecialTokens) []string {
+	symbols := make([]string, 0, utf8.RuneCountInString(word))
+	for _, r := range word {
+		symbols = append(symbols, string(r))
+	}
+
+	if len(symbols) == 0 {
+		return symbols
+	}
+
+	symbols[0] = special.BeginOfWord + symbols[0]
+	symbols[len(symbols)-1] += special.EndOfWord
+
+	return symbols
+}
+
+// applyMerge replaces all occurrences of the pair in symbols with merged symbol.
+func applyMerge(symbols []string, pair mergePair, merged string) []string {
+	result := symbols[:0]
+
+	for i := 0; i < len(symbols); i++ {
+		if i+1 < len(symbols) && symbols[i] == pair.Left && symbols[i+1] == pair.Right {
+			result = append(result, merged)
+			i++
+
+			continue
+		}
+
+		result = append(result, symbols[i])
+	}
+
+	return result
+}
+
+// sortByFrequency returns tokens ordered by frequency. Tokens with equal frequency are ordered lexicographically.
+func sortByFrequency(tft tokensFrequencyTable) []string {
+	tokens := make([]string, 0, len(tft))
+	for t := range tft {
+		tokens = append(tokens, t)
+	}
+
+	sort.Slice(tokens, func(i, j int) bool {
+		if tft[tokens[i]] != tft[tokens[j]] {
+			return tft[tokens[i]] > tft[tokens[j]]
+		}
+
+		return tokens[i] < tokens[j]
+	})
+
+	return tokens
+}
+
+// pairStats keeps frequencies of adjacent pairs and the words they occur in.
+type pairStats struct {
+	maxTokenLength int
+	symbolLength   map[string]int
+	counts         map[mergePair]int
+	where          map[mergePair]map[int]struct{}
+	queue          pairQueue
+}
+
+func newPairStats(maxTokenLength int, symbolLength map[string]int) *pairStats {
+	return &pairStats{
+		maxTokenLength: maxTokenLength,
+		symbolLength:   symbolLength,
+		counts:         make(map[mergePair]int),
+		where:          make(map[mergePair]map[int]struct{}),
+	}
+}
+
+func (s *pairStats) addWord(index int, w mergeWord) {
+	s.updateWord(index, w, w.count)
+}
+
+func (s *pairStats) removeWord(index int, w mergeWord) {
+	s.updateWord(index, w, -w.count)
+}
+
+func (s *pairStats) updateWord(index int, w mergeWord, delta int) {
+	for i := 0; i+1 < len(w.symbols); i++ {
+		pair := mergePair{Left: w.symbols[i], Right: w.symbols[i+1]}
+
+		// Symbols out of the alphabet are never merged.
+		if s.symbolLength[pair.Left] == 0 || s.symbolLength[pair.Right] == 0 {
+			continue
+		}
+
+		// Too long tokens will never be added to the vocabulary so there is no need to count them.
+		if s.symbolLength[pair.Left]+s.symbolLength[pair.Right] > s.maxTokenLength {
+			continue
+		}
+
+		s.counts[pair] += delta
+
+		if delta > 0 {
+			if s.where[pair] == nil {
+				s.where[pair] = make(map[int]struct{})
+			}
+
+			s.where[pair][index] = struct{}{}
+		} else {
+			delete(s.where[pair], index)
+		}
+
+		if s.counts[pair] > 0 {
+			heap.Push(&s.queue, weightedPair{pair: pair, weight: s.counts[pair]})
+		} else {
+			delete(s.counts, pair)
+			delete(s.where, pair)
+		}
+	}
+}
+
+// best returns the most frequent pair. Outdated queue entries are dropped on the fly.
+func (s *pairStats) best() (mergePair, bool) {
+	for s.queue.Len() > 0 {
+		candidate := heap.Pop(&s.queue).(weightedPair)
+
+		if s.counts[candidate.pair] == candidate.weight {
+			return candidate.pair, true
+		}
+	}
+
+	return mergePair{}, false
+}
+
+// wordsWith returns sorted indexes of words which contain the pair.
+func (s *pairStats) wordsWith(pair mergePair) []int {
+	indexes := make([]int, 0, len(s.where[pair]))
+	for i := range s.where[pair] {
+		indexes = append(indexes, i)
+	}
+
+	sort.Ints(indexes)
+
+	return indexes
+}
+
+type weightedPair struct {
+	pair   mergePair
+	weight int
+}
+
+// pairQueue is a max heap of pairs. Pairs with equal weight are ordered lexicographically.
+type pairQueue []weightedPair
+
+func (q pairQueue) Len() int { return len(q) }
+
+func (q pairQueue) Less(i, j int) bool {
+	if q[i].weight != q[j].weight {
+		return q[i].weight > q[j].weight
+	}
+
+	if q[i].pair.Left != q[j].pair.Left {
+		return q[i].pair.Left < q[j].pair.Left
+	}
+
+	return q[i].pair.Right < q[j].pair.Right
+}
+
+func (q pairQueue) Swap(i, j int) { q[i], q[j] = q[j], q[i] }
+
+func (q *pairQueue) Push(x interface{}) { *q = append(*q, x.(weightedPair)) }
+
+func (q *pairQueue) Pop() interface{} {
+	old := *q
+	item := old[len(old)-1]
+	*q = old[:len(old)-1]
+
+	return item
+}
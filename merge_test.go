@@ -0,0 +1,206 @@
+package bpe
+
+import (
+	"context"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestLearnMerges(t *testing.T) {
+	tt := []struct {
+		name           string
+		words          tokensFrequencyTable
+		tokensLimit    int
+		maxTokenLength int
+		minFrequency   int
+		expectedVocab  []string
+		expectedMerges []mergePair
+	}{
+		{
+			name:           "single word",
+			words:          tokensFrequencyTable{"ab": 1},
+			tokensLimit:    10,
+			maxTokenLength: 10,
+			expectedVocab:  []string{BeginOfWord + "a", "b" + EndOfWord, BeginOfWord + "ab" + EndOfWord},
+			expectedMerges: []mergePair{
+				{Left: BeginOfWord + "a", Right: "b" + EndOfWord},
+			},
+		},
+		{
+			name:           "most frequent pair first",
+			words:          tokensFrequencyTable{"aab": 1, "cab": 3},
+			tokensLimit:    6,
+			maxTokenLength: 10,
+			expectedVocab: []string{
+				"a", "b" + EndOfWord, BeginOfWord + "c", BeginOfWord + "a",
+				"ab" + EndOfWord, BeginOfWord + "cab" + EndOfWord,
+			},
+			expectedMerges: []mergePair{
+				{Left: "a", Right: "b" + EndOfWord},
+				{Left: BeginOfWord + "c", Right: "ab" + EndOfWord},
+			},
+		},
+		{
+			name:           "alphabet exceeds limit",
+			words:          tokensFrequencyTable{"abc": 1, "b": 2},
+			tokensLimit:    2,
+			maxTokenLength: 10,
+			expectedVocab:  []string{BeginOfWord + "b" + EndOfWord, BeginOfWord + "a", "b", "c" + EndOfWord},
+		},
+		{
+			name:           "max token length",
+			words:          tokensFrequencyTable{"abc": 1},
+			tokensLimit:    10,
+			maxTokenLength: 2,
+			expectedVocab:  []string{BeginOfWord + "a", "b", "c" + EndOfWord, BeginOfWord + "ab"},
+			expectedMerges: []mergePair{
+				{Left: BeginOfWord + "a", Right: "b"},
+			},
+		},
+		{
+			name:           "min frequency",
+			words:          tokensFrequencyTable{"ab": 1, "cd": 2},
+			tokensLimit:    10,
+			maxTokenLength: 10,
+			minFrequency:   2,
+			expectedVocab: []string{
+				BeginOfWord + "c", "d" + EndOfWord, BeginOfWord + "a", "b" + EndOfWord,
+				BeginOfWord + "cd" + EndOfWord,
+			},
+			expectedMerges: []mergePair{
+				{Left: BeginOfWord + "c", Right: "d" + EndOfWord},
+			},
+		},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			options := defaultTrainOptions()
+			options.MaxNumberOfTokens = tc.tokensLimit
+			options.MaxTokenLength = tc.maxTokenLength
+			options.MinFrequency = tc.minFrequency
+			vocab, merges := learnMerges(tc.words, options)
+
+			if !reflect.DeepEqual(tc.expectedVocab, vocab) {
+				t.Errorf("Expected vocab: %v\nGot: %v\n", tc.expectedVocab, vocab)
+			}
+
+			if !reflect.DeepEqual(tc.expectedMerges, merges) {
+				t.Errorf("Expected merges: %v\nGot: %v\n", tc.expectedMerges, merges)
+			}
+		})
+	}
+}
+
+func TestApplyMerge(t *testing.T) {
+	tt := []struct {
+		name     string
+		symbols  []string
+		pair     mergePair
+		expected []string
+	}{
+		{
+			name:     "no pair",
+			symbols:  []string{"a", "b"},
+			pair:     mergePair{Left: "b", Right: "a"},
+			expected: []string{"a", "b"},
+		},
+		{
+			name:     "several occurrences",
+			symbols:  []string{"a", "b", "c", "a", "b"},
+			pair:     mergePair{Left: "a", Right: "b"},
+			expected: []string{"ab", "c", "ab"},
+		},
+		{
+			name:     "overlapping pairs",
+			symbols:  []string{"a", "a", "a"},
+			pair:     mergePair{Left: "a", Right: "a"},
+			expected: []string{"aa", "a"},
+		},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			pair := tc.pair
+			actual := applyMerge(tc.symbols, pair, pair.Left+pair.Right)
+
+			if !reflect.DeepEqual(tc.expected, actual) {
+				t.Errorf("Expected: %v\nGot: %v\n", tc.expected, actual)
+			}
+		})
+	}
+}
+
+func TestBPE_applyMerges(t *testing.T) {
+	b := &BPE{
+		merges: map[mergePair]int{
+			{Left: "l", Right: "o"}:  2,
+			{Left: "o", Right: "w"}:  0,
+			{Left: "l", Right: "ow"}: 1,
+			{Left: "lo", Right: "w"}: 3,
+		},
+	}
+
+	tt := []struct {
+		name     string
+		symbols  []string
+		expected []string
+	}{
+		{
+			name:     "lowest rank first",
+			symbols:  []string{"l", "o", "w"},
+			expected: []string{"low"},
+		},
+		{
+			name:     "partial",
+			symbols:  []string{"l", "o", "l"},
+			expected: []string{"lo", "l"},
+		},
+		{
+			name:     "nothing to merge",
+			symbols:  []string{"w", "o"},
+			expected: []string{"w", "o"},
+		},
+		{
+			name:     "empty",
+			symbols:  []string{},
+			expected: []string{},
+		},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			actual := b.applyMerges(tc.symbols)
+
+			if !reflect.DeepEqual(tc.expected, actual) {
+				t.Errorf("Expected: %v\nGot: %v\n", tc.expected, actual)
+			}
+		})
+	}
+}
+
+func TestTrain_EncodeWithLearnedMerges(t *testing.T) {
+	corpus := "low lower lowest newer newest wider"
+
+	model, err := Train(context.Background(), strings.NewReader(corpus), WithMaxNumberOfTokens(20))
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	tokens, err := model.Encode(strings.NewReader("lowest newer"))
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	expected := []string{
+		BeginOfSentence,
+		BeginOfWord + "lowe", "st" + EndOfWord,
+		BeginOfWord + "newe", "r" + EndOfWord,
+		EndOfSentence,
+	}
+
+	if !reflect.DeepEqual(expected, tokens) {
+		t.Errorf("Expected: %v\nGot: %v\n", expected, tokens)
+	}
+}
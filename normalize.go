@@ -0,0 +1,183 @@
+package bpe
+
+import (
+	"strings"
+	"sync"
+	"unicode"
+
+	"github.com/pkg/errors"
+	"golang.org/x/text/unicode/norm"
+)
+
+// Normalizer transforms text before it's split into tokens.
+// Normalizers are applied to every sentence both by training and by encoding,
+// so model always sees text the same way. Name identifies normalizer in exported model.
+type Normalizer interface {
+	Name() string
+	Normalize(text string) string
+}
+
+// NormalizerFunc makes normalizer from a function. Custom normalizers must be registered
+// with RegisterNormalizer to import models which use them.
+func NormalizerFunc(name string, normalize func(text string) string) Normalizer {
+	return &normalizerFunc{name: name, normalize: normalize}
+}
+
+type normalizerFunc struct {
+	name      string
+	normalize func(text string) string
+}
+
+func (n *normalizerFunc) Name() string {
+	return n.name
+}
+
+func (n *normalizerFunc) Normalize(text string) string {
+	return n.normalize(text)
+}
+
+// NFC composes characters, so "é" written as "e" and combining accent becomes a single character.
+func NFC() Normalizer {
+	return NormalizerFunc("nfc", norm.NFC.String)
+}
+
+// NFKC works like NFC but also replaces compatibility characters like "ﬁ" or "①" with their equivalents.
+func NFKC() Normalizer {
+	return NormalizerFunc("nfkc", norm.NFKC.String)
+}
+
+// Lowercase maps all letters to lower case.
+func Lowercase() Normalizer {
+	return NormalizerFunc("lowercase", strings.ToLower)
+}
+
+// StripAccents removes accents and other combining marks, so "café" becomes "cafe".
+func StripAccents() Normalizer {
+	return NormalizerFunc("strip_accents", stripAccents)
+}
+
+func stripAccents(text string) string {
+	decomposed := norm.NFD.String(text)
+
+	stripped := strings.Map(func(r rune) rune {
+		if unicode.Is(unicode.Mn, r) {
+			return -1
+		}
+
+		return r
+	}, decomposed)
+
+	return norm.NFC.String(stripped)
+}
+
+// RemoveControl removes control and format characters like zero width spaces. Tabs and line breaks are kept.
+func RemoveControl() Normalizer {
+	return NormalizerFunc("remove_control", removeControl)
+}
+
+func removeControl(text string) string {
+	return strings.Map(func(r rune) rune {
+		if r == '\t' || r == '\n' || r == '\r' {
+			return r
+		}
+
+		if unicode.IsControl(r) || unicode.Is(unicode.Cf, r) {
+			return -1
+		}
+
+		return r
+	}, text)
+}
+
+// CollapseWhitespace replaces every run of whitespace characters with a single space.
+func CollapseWhitespace() Normalizer {
+	return NormalizerFunc("collapse_whitespace", collapseWhitespace)
+}
+
+func collapseWhitespace(text string) string {
+	builder := strings.Builder{}
+	builder.Grow(len(text))
+	inSpace := false
+
+	for _, r := range text {
+		if unicode.IsSpace(r) {
+			if !inSpace {
+				builder.WriteByte(' ')
+			}
+
+			inSpace = true
+
+			continue
+		}
+
+		builder.WriteRune(r)
+		inSpace = false
+	}
+
+	return builder.String()
+}
+
+var (
+	normalizersMu sync.RWMutex
+	normalizers   = map[string]Normalizer{}
+)
+
+func init() {
+	for _, n := range []Normalizer{NFC(), NFKC(), Lowercase(), StripAccents(), RemoveControl(), CollapseWhitespace()} {
+		RegisterNormalizer(n)
+	}
+}
+
+// RegisterNormalizer makes normalizer available for import by its name.
+// Normalizer with the same name is replaced.
+func RegisterNormalizer(n Normalizer) {
+	normalizersMu.Lock()
+	defer normalizersMu.Unlock()
+
+	normalizers[n.Name()] = n
+}
+
+// lookupNormalizers returns registered normalizers by names.
+func lookupNormalizers(names []string) ([]Normalizer, error) {
+	normalizersMu.RLock()
+	defer normalizersMu.RUnlock()
+
+	var result []Normalizer
+
+	for _, name := range names {
+		n, ok := normalizers[name]
+		if !ok {
+			return nil, errors.Errorf("unknown normalizer %q", name)
+		}
+
+		result = append(result, n)
+	}
+
+	return result, nil
+}
+
+func normalizerNames(normalizers []Normalizer) []string {
+	var names []string
+	for _, n := range normalizers {
+		names = append(names, n.Name())
+	}
+
+	return names
+}
+
+// normalize applies normalizers in order.
+func normalize(text string, normalizers []Normalizer) string {
+	for _, n := range normalizers {
+		text = n.Normalize(text)
+	}
+
+	return text
+}
+
+// WithNormalizers sets normalizers of the model. They are applied in the given order.
+// Normalizers are exported with the model and used by Encode.
+func WithNormalizers(normalizers ...Normalizer) TrainOption {
+	return func(opts *trainOptions) {
+		opts.Normalizers = append(opts.Normalizers, normalizers...)
+	}
+}
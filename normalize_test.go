@@ -0,0 +1,103 @@
+package bpe
+
+import (
+	"bytes"
+	"context"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestNormalizers(t *testing.T) {
+	tt := []struct {
+		normalizer Normalizer
+		input      string
+		expected   string
+	}{
+		{normalizer: NFC(), input: "Cafe\u0301", expected: "Caf\u00e9"},
+		{normalizer: NFKC(), input: "ﬁle ①", expected: "file 1"},
+		{normalizer: Lowercase(), input: "ПриВет World", expected: "привет world"},
+		{normalizer: StripAccents(), input: "Caf\u00e9 Cafe\u0301 ёж", expected: "Cafe Cafe еж"},
+		{normalizer: RemoveControl(), input: "a\u200bb\x00c\td\n", expected: "abc\td\n"},
+		{normalizer: CollapseWhitespace(), input: " a \t\n b  c ", expected: " a b c "},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.normalizer.Name(), func(t *testing.T) {
+			actual := tc.normalizer.Normalize(tc.input)
+
+			if actual != tc.expected {
+				t.Errorf("Expected: %q\nGot: %q\n", tc.expected, actual)
+			}
+		})
+	}
+}
+
+func TestTrain_WithNormalizers(t *testing.T) {
+	corpus := "Caf\u00e9 CAFE\u0301 cafe\u0301"
+
+	model, err := Train(context.Background(), strings.NewReader(corpus), WithNormalizers(NFC(), Lowercase()))
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	expected := []string{BeginOfSentence, BeginOfWord + "caf\u00e9" + EndOfWord, EndOfSentence}
+
+	for _, text := range []string{"Caf\u00e9", "Cafe\u0301", "CAF\u00c9"} {
+		tokens, err := model.Encode(strings.NewReader(text))
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+
+		if !reflect.DeepEqual(expected, tokens) {
+			t.Errorf("Text %q. Expected: %v\nGot: %v\n", text, expected, tokens)
+		}
+	}
+}
+
+func TestNormalizers_ExportImport(t *testing.T) {
+	RegisterNormalizer(NormalizerFunc("test_dashes", func(text string) string {
+		return strings.ReplaceAll(text, "—", "-")
+	}))
+
+	normalizers, err := lookupNormalizers([]string{"test_dashes", "nfkc"})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	model, err := Train(context.Background(), strings.NewReader("a-b"), WithNormalizers(normalizers...))
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	buf := bytes.NewBuffer(nil)
+	if err := Export(model, buf); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	imported, err := Import(buf)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	expected := []string{"test_dashes", "nfkc"}
+	if actual := normalizerNames(imported.normalizers); !reflect.DeepEqual(expected, actual) {
+		t.Errorf("Expected: %v\nGot: %v\n", expected, actual)
+	}
+
+	tokens, err := imported.Encode(strings.NewReader("a—b"))
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	if tokens[1] != BeginOfWord+"a-b"+EndOfWord {
+		t.Errorf("Normalized word expected. Got: %v", tokens)
+	}
+}
+
+func TestImport_UnknownNormalizer(t *testing.T) {
+	_, err := Import(strings.NewReader(`{"max_token_length":3,"vocab":["foo"],"normalizers":["missing"]}`))
+	if err == nil {
+		t.Error("Error expected")
+	}
+}
@@ -0,0 +1,233 @@
+package bpe
+
+import (
+	"bufio"
+	"io"
+	"sort"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// Span is a part of the original text which token comes from. Start is inclusive, end is exclusive.
+// Tokens which don't come from text, like begin and end of sentence, have zero span.
+// Word boundary markers aren't part of the text, so tokens which consist of them only have empty span.
+type Span struct {
+	Start     int // Byte offset.
+	End       int
+	RuneStart int // Rune offset.
+	RuneEnd   int
+}
+
+// Empty checks whether span covers no text.
+func (s Span) Empty() bool {
+	return s.Start == s.End
+}
+
+// EncodeWithOffsets works like Encode but also returns span of the original text for every token.
+// Spans of tokens which come from normalized text cover the original characters they come from.
+func (b *BPE) EncodeWithOffsets(r io.Reader, opts ...EncodeOption) ([]string, []Span, error) {
+	options := defaultEncodeOptions()
+	options.Apply(opts...)
+
+	result, err := b.encode(r, options, true)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return result.tokens, result.spans, nil
+}
+
+// realign maps spans of tokens starting from the first one from normalized text to the original one.
+// Base is offset of the original text.
+func (e *encoded) realign(first int, a alignment, base int) {
+	if !e.detailed {
+		return
+	}
+
+	for i := first; i < len(e.spans); i++ {
+		span := &e.spans[i]
+		if span.Start == noSpan {
+			continue
+		}
+
+		span.Start, span.End = a.start(span.Start)+base, a.end(span.End)+base
+		if span.End < span.Start {
+			span.End = span.Start
+		}
+	}
+}
+
+// locate converts spans of tokens of the sentence starting from the first one to offsets in the source.
+func (e *encoded) locate(first int, sentence string, start sourcePosition) {
+	if !e.detailed {
+		return
+	}
+
+	runes := runeIndex(sentence)
+
+	for i := first; i < len(e.spans); i++ {
+		span := &e.spans[i]
+		if span.Start == noSpan {
+			*span = Span{}
+			continue
+		}
+
+		runeStart := runes[span.Start]
+		if span.Start < len(sentence) && !utf8.RuneStart(sentence[span.Start]) {
+			runeStart-- // Span starts inside of a rune.
+		}
+
+		*span = Span{
+			Start:     start.bytes + span.Start,
+			End:       start.bytes + span.End,
+			RuneStart: start.runes + runeStart,
+			RuneEnd:   start.runes + runes[span.End],
+		}
+	}
+}
+
+// runeIndex returns number of runes which start before every byte offset of the text including its length.
+func runeIndex(text string) []int {
+	index := make([]int, len(text)+1)
+	runes := 0
+
+	for i := 0; i < len(text); i++ {
+		index[i] = runes
+
+		if utf8.RuneStart(text[i]) {
+			runes++
+		}
+	}
+
+	index[len(text)] = runes
+
+	return index
+}
+
+// sourcePosition is a position in the source.
+type sourcePosition struct {
+	bytes int
+	runes int
+}
+
+// trackSentences wraps split function to track position of the last sentence in the source.
+func trackSentences(split bufio.SplitFunc, sentence *sourcePosition) bufio.SplitFunc {
+	var consumed sourcePosition
+
+	return func(data []byte, atEOF bool) (int, []byte, error) {
+		advance, token, err := split(data, atEOF)
+		if err != nil || advance == 0 {
+			return advance, token, err
+		}
+
+		if token != nil {
+			tokenStart := advance - len(token)
+			*sentence = sourcePosition{
+				bytes: consumed.bytes + tokenStart,
+				runes: consumed.runes + utf8.RuneCount(data[:tokenStart]),
+			}
+		}
+
+		consumed.bytes += advance
+		consumed.runes += utf8.RuneCount(data[:advance])
+
+		return advance, token, err
+	}
+}
+
+// alignment maps byte offsets of normalized text to the original one. Nil alignment means the texts are equal.
+type alignment []alignedChunk
+
+// alignedChunk is a part of the original text and the normalized text it has become.
+type alignedChunk struct {
+	normalizedStart int
+	normalizedEnd   int
+	originalStart   int
+	originalEnd     int
+}
+
+// start maps offset of span start. Spans which start inside of a chunk start at the beginning of it.
+func (a alignment) start(offset int) int {
+	if a == nil {
+		return offset
+	}
+
+	i := sort.Search(len(a), func(i int) bool {
+		return a[i].normalizedEnd > offset
+	})
+
+	if i == len(a) {
+		return a[len(a)-1].originalEnd
+	}
+
+	return a[i].originalStart
+}
+
+// end maps offset of span end. Spans which end inside of a chunk end at the end of it.
+func (a alignment) end(offset int) int {
+	if a == nil {
+		return offset
+	}
+
+	if offset == 0 {
+		return a[0].originalStart
+	}
+
+	i := sort.Search(len(a), func(i int) bool {
+		return a[i].normalizedEnd >= offset
+	})
+
+	if i == len(a) {
+		return a[len(a)-1].originalEnd
+	}
+
+	return a[i].originalEnd
+}
+
+// normalizeAligned applies normalizers and returns alignment of the result if it's requested.
+// Text is normalized chunk by chunk, where chunk is a character with its combining marks or a run of spaces.
+// If that gives other result than normalization of the whole text, the whole text becomes a single chunk.
+func normalizeAligned(text string, normalizers []Normalizer, aligned bool) (string, alignment) {
+	normalized := normalize(text, normalizers)
+	if !aligned || normalized == text {
+		return normalized, nil
+	}
+
+	var (
+		result  alignment
+		builder strings.Builder
+	)
+
+	for start := 0; start < len(text); {
+		r, width := utf8.DecodeRuneInString(text[start:])
+		space := unicode.IsSpace(r)
+		end := start + width
+
+		for end < len(text) {
+			next, nextWidth := utf8.DecodeRuneInString(text[end:])
+			if space != unicode.IsSpace(next) || (!space && !unicode.In(next, unicode.Mn, unicode.Mc, unicode.Me)) {
+				break
+			}
+
+			end += nextWidth
+		}
+
+		chunk := normalize(text[start:end], normalizers)
+		result = append(result, alignedChunk{
+			normalizedStart: builder.Len(),
+			normalizedEnd:   builder.Len() + len(chunk),
+			originalStart:   start,
+			originalEnd:     end,
+		})
+
+		builder.WriteString(chunk)
+		start = end
+	}
+
+	if builder.String() != normalized || len(result) == 0 {
+		return normalized, alignment{{normalizedEnd: len(normalized), originalEnd: len(text)}}
+	}
+
+	return normalized, result
+}
@@ -0,0 +1,191 @@
+package bpe
+
+import (
+	"context"
+	"io/ioutil"
+	"reflect"
+	"strings"
+	"testing"
+	"unicode/utf8"
+)
+
+func TestBPE_EncodeWithOffsets(t *testing.T) {
+	corpus, err := ioutil.ReadFile("example.txt")
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	text := "  Hello, wörld!  It's 3.14.\n\tBye [URL] now. "
+
+	tt := []struct {
+		name    string
+		options []TrainOption
+	}{
+		{name: "merges"},
+		{name: "substring frequency", options: []TrainOption{WithSubstringFrequency()}},
+		{name: "byte level", options: []TrainOption{WithByteLevel()}},
+		{name: "pre-tokenizers", options: []TrainOption{WithPreTokenizers(Punctuation(), Digits())}},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			options := append([]TrainOption{WithMaxNumberOfTokens(200)}, tc.options...)
+
+			model, err := Train(context.Background(), strings.NewReader(string(corpus)), options...)
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+
+			model.AddTokens(AddedToken{Content: "[URL]"})
+
+			tokens, spans, err := model.EncodeWithOffsets(strings.NewReader(text))
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+
+			expected, err := model.Encode(strings.NewReader(text))
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+
+			if !reflect.DeepEqual(expected, tokens) {
+				t.Fatalf("Tokens differ from Encode.\nExpected: %v\nGot: %v\n", expected, tokens)
+			}
+
+			if len(spans) != len(tokens) {
+				t.Fatalf("Expected %d spans. Got: %d", len(tokens), len(spans))
+			}
+
+			for i, token := range tokens {
+				span := spans[i]
+
+				if token == BeginOfSentence || token == EndOfSentence {
+					if span != (Span{}) {
+						t.Errorf("Zero span expected for %q. Got: %+v", token, span)
+					}
+
+					continue
+				}
+
+				// Unknown token could replace a character or a word boundary marker.
+				if token == UnknownToken {
+					continue
+				}
+
+				if actual := text[span.Start:span.End]; actual != tokenText(model, token) {
+					t.Errorf("Token %q. Expected text: %q\nGot: %q\n", token, tokenText(model, token), actual)
+				}
+
+				// Spans of byte-level tokens could start or end inside of a rune.
+				if !utf8.RuneStart(text[span.Start]) || (span.End < len(text) && !utf8.RuneStart(text[span.End])) {
+					continue
+				}
+
+				if span.RuneStart != utf8.RuneCountInString(text[:span.Start]) ||
+					span.RuneEnd != utf8.RuneCountInString(text[:span.End]) {
+					t.Errorf("Token %q. Wrong rune span: %+v", token, span)
+				}
+			}
+		})
+	}
+}
+
+// tokenText returns text which token comes from.
+func tokenText(model *BPE, token string) string {
+	if model.byteLevel {
+		return string(fromByteLevel(token))
+	}
+
+	token = strings.TrimPrefix(token, BeginOfWord)
+
+	return strings.TrimSuffix(token, EndOfWord)
+}
+
+func TestBPE_EncodeWithOffsets_Unknowns(t *testing.T) {
+	model := newModel([]string{BeginOfWord + "a", "b" + EndOfWord})
+	text := "aЖЖb"
+
+	tt := []struct {
+		name     string
+		options  []EncodeOption
+		expected []Span
+	}{
+		{
+			name: "unknown per character",
+			expected: []Span{
+				{},
+				{Start: 0, End: 1, RuneStart: 0, RuneEnd: 1},
+				{Start: 1, End: 3, RuneStart: 1, RuneEnd: 2},
+				{Start: 3, End: 5, RuneStart: 2, RuneEnd: 3},
+				{Start: 5, End: 6, RuneStart: 3, RuneEnd: 4},
+				{},
+			},
+		},
+		{
+			name:    "collapsed unknowns",
+			options: []EncodeOption{WithCollapsedUnknowns()},
+			expected: []Span{
+				{},
+				{Start: 0, End: 1, RuneStart: 0, RuneEnd: 1},
+				{Start: 1, End: 5, RuneStart: 1, RuneEnd: 3},
+				{Start: 5, End: 6, RuneStart: 3, RuneEnd: 4},
+				{},
+			},
+		},
+		{
+			name:    "byte fallback",
+			options: []EncodeOption{WithByteFallback()},
+			expected: []Span{
+				{},
+				{Start: 0, End: 1, RuneStart: 0, RuneEnd: 1},
+				{Start: 1, End: 2, RuneStart: 1, RuneEnd: 2},
+				{Start: 2, End: 3, RuneStart: 1, RuneEnd: 2},
+				{Start: 3, End: 4, RuneStart: 2, RuneEnd: 3},
+				{Start: 4, End: 5, RuneStart: 2, RuneEnd: 3},
+				{Start: 5, End: 6, RuneStart: 3, RuneEnd: 4},
+				{},
+			},
+		},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			_, spans, err := model.EncodeWithOffsets(strings.NewReader(text), tc.options...)
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+
+			if !reflect.DeepEqual(tc.expected, spans) {
+				t.Errorf("Expected: %+v\nGot: %+v\n", tc.expected, spans)
+			}
+		})
+	}
+}
+
+func TestBPE_EncodeWithOffsets_Normalizers(t *testing.T) {
+	model := newModel([]string{BeginOfWord + "caf\u00e9" + EndOfWord, BeginOfWord + "fi" + EndOfWord})
+	model.normalizers = []Normalizer{NFKC(), Lowercase(), RemoveControl()}
+
+	text := "CAF\u00c9\u200b \ufb01 x"
+
+	tokens, spans, err := model.EncodeWithOffsets(strings.NewReader(text), WithCollapsedUnknowns())
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	expectedTokens := []string{
+		BeginOfSentence, BeginOfWord + "caf\u00e9" + EndOfWord, BeginOfWord + "fi" + EndOfWord, UnknownToken, EndOfSentence,
+	}
+
+	if !reflect.DeepEqual(expectedTokens, tokens) {
+		t.Fatalf("Expected: %v\nGot: %v\n", expectedTokens, tokens)
+	}
+
+	expected := []string{"", "CAF\u00c9", "\ufb01", "x", ""}
+
+	for i, span := range spans {
+		if actual := text[span.Start:span.End]; actual != expected[i] {
+			t.Errorf("Token %q. Expected: %q\nGot: %q\n", tokens[i], expected[i], actual)
+		}
+	}
+}
@@ -0,0 +1,217 @@
+package bpe
+
+import (
+	"regexp"
+	"strings"
+	"sync"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/pkg/errors"
+)
+
+// PreTokenizer splits words into smaller words before they are split into tokens.
+// Text is always split by whitespace first, so PreTokenize gets words without spaces.
+// Concatenation of the returned parts must give the word back, otherwise byte-level models lose text.
+// Tokens never cross boundaries of parts. Name identifies pre-tokenizer in exported model.
+type PreTokenizer interface {
+	Name() string
+	PreTokenize(word string) []string
+}
+
+// PreTokenizerFunc makes pre-tokenizer from a function. Custom pre-tokenizers must be registered
+// with RegisterPreTokenizer to import models which use them.
+func PreTokenizerFunc(name string, preTokenize func(word string) []string) PreTokenizer {
+	return &preTokenizerFunc{name: name, preTokenize: preTokenize}
+}
+
+type preTokenizerFunc struct {
+	name        string
+	preTokenize func(word string) []string
+}
+
+func (p *preTokenizerFunc) Name() string {
+	return p.name
+}
+
+func (p *preTokenizerFunc) PreTokenize(word string) []string {
+	return p.preTokenize(word)
+}
+
+// Whitespace keeps words split by whitespace as is. It's the default behaviour.
+func Whitespace() PreTokenizer {
+	return PreTokenizerFunc("whitespace", func(word string) []string {
+		return []string{word}
+	})
+}
+
+// Punctuation splits every punctuation character off, so "example." becomes "example" and ".".
+func Punctuation() PreTokenizer {
+	return PreTokenizerFunc("punctuation", func(word string) []string {
+		return splitRunes(word, unicode.IsPunct)
+	})
+}
+
+// Digits splits every digit off, so numbers are encoded digit by digit.
+func Digits() PreTokenizer {
+	return PreTokenizerFunc("digits", func(word string) []string {
+		return splitRunes(word, unicode.IsDigit)
+	})
+}
+
+// CJK splits every Chinese, Japanese and Korean character off. These languages don't separate words with spaces.
+func CJK() PreTokenizer {
+	return PreTokenizerFunc("cjk", func(word string) []string {
+		return splitRunes(word, isCJK)
+	})
+}
+
+func isCJK(r rune) bool {
+	return unicode.In(r, unicode.Han, unicode.Hiragana, unicode.Katakana, unicode.Hangul)
+}
+
+// gpt2Pattern is the pattern of GPT-2 without spaces which are handled separately.
+var gpt2Pattern = regexp.MustCompile(`'(?:s|t|re|ve|m|ll|d)|\p{L}+|\p{N}+|[^\s\p{L}\p{N}]+`)
+
+// GPT2 splits words the way GPT-2 does: into runs of letters, runs of numbers, runs of other characters
+// and English contractions like "'s" or "'ll".
+func GPT2() PreTokenizer {
+	return PreTokenizerFunc("gpt2", func(word string) []string {
+		return gpt2Pattern.FindAllString(word, -1)
+	})
+}
+
+// splitRunes splits every rune which matches isolated from the rest of the word.
+func splitRunes(word string, isolated func(r rune) bool) []string {
+	var parts []string
+	start := 0
+
+	for i, r := range word {
+		if !isolated(r) {
+			continue
+		}
+
+		if start < i {
+			parts = append(parts, word[start:i])
+		}
+
+		end := i + utf8.RuneLen(r)
+		parts = append(parts, word[i:end])
+		start = end
+	}
+
+	if start < len(word) {
+		parts = append(parts, word[start:])
+	}
+
+	return parts
+}
+
+var (
+	preTokenizersMu sync.RWMutex
+	preTokenizers   = map[string]PreTokenizer{}
+)
+
+func init() {
+	for _, p := range []PreTokenizer{Whitespace(), Punctuation(), Digits(), CJK(), GPT2()} {
+		RegisterPreTokenizer(p)
+	}
+}
+
+// RegisterPreTokenizer makes pre-tokenizer available for import by its name.
+// Pre-tokenizer with the same name is replaced.
+func RegisterPreTokenizer(p PreTokenizer) {
+	preTokenizersMu.Lock()
+	defer preTokenizersMu.Unlock()
+
+	preTokenizers[p.Name()] = p
+}
+
+// lookupPreTokenizers returns registered pre-tokenizers by names.
+func lookupPreTokenizers(names []string) ([]PreTokenizer, error) {
+	preTokenizersMu.RLock()
+	defer preTokenizersMu.RUnlock()
+
+	var result []PreTokenizer
+
+	for _, name := range names {
+		p, ok := preTokenizers[name]
+		if !ok {
+			return nil, errors.Errorf("unknown pre-tokenizer %q", name)
+		}
+
+		result = append(result, p)
+	}
+
+	return result, nil
+}
+
+func preTokenizerNames(preTokenizers []PreTokenizer) []string {
+	var names []string
+	for _, p := range preTokenizers {
+		names = append(names, p.Name())
+	}
+
+	return names
+}
+
+// preTokenize splits text into words by whitespace and then by pre-tokenizers in order.
+// Reserved tokens are never split. If keepSpaces is set spaces are kept as a prefix of the following word,
+// so concatenation of the words gives the original text.
+func preTokenize(text string, preTokenizers []PreTokenizer, special SpecialTokens, keepSpaces bool) []string {
+	fields := strings.Fields(text)
+	if keepSpaces {
+		fields = splitWithSpaces(text)
+	}
+
+	if len(preTokenizers) == 0 {
+		return fields
+	}
+
+	words := make([]string, 0, len(fields))
+
+	for _, field := range fields {
+		word := strings.TrimLeftFunc(field, unicode.IsSpace)
+		if word == "" || special.isReserved(word) {
+			words = append(words, field)
+			continue
+		}
+
+		parts := []string{word}
+		for _, p := range preTokenizers {
+			parts = splitParts(parts, p)
+		}
+
+		if len(parts) == 0 {
+			continue
+		}
+
+		parts[0] = field[:len(field)-len(word)] + parts[0]
+		words = append(words, parts...)
+	}
+
+	return words
+}
+
+// splitParts splits every part with pre-tokenizer. Empty parts are dropped.
+func splitParts(parts []string, p PreTokenizer) []string {
+	result := make([]string, 0, len(parts))
+
+	for _, part := range parts {
+		for _, split := range p.PreTokenize(part) {
+			if split != "" {
+				result = append(result, split)
+			}
+		}
+	}
+
+	return result
+}
+
+// WithPreTokenizers sets pre-tokenizers of the model. They are applied in the given order,
+// each one splits parts left by the previous one. Pre-tokenizers are exported with the model and used by Encode.
+func WithPreTokenizers(preTokenizers ...PreTokenizer) TrainOption {
+	return func(opts *trainOptions) {
+		opts.PreTokenizers = append(opts.PreTokenizers, preTokenizers...)
+	}
+}
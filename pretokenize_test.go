@@ -0,0 +1,195 @@
+package bpe
+
+import (
+	"bytes"
+	"context"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestPreTokenize(t *testing.T) {
+	special := DefaultSpecialTokens()
+	special.Reserved = []string{"[MASK]"}
+
+	tt := []struct {
+		name          string
+		text          string
+		preTokenizers []PreTokenizer
+		keepSpaces    bool
+		expected      []string
+	}{
+		{
+			name:     "default",
+			text:     " Hello, world! ",
+			expected: []string{"Hello,", "world!"},
+		},
+		{
+			name:       "default with spaces",
+			text:       " Hello, world! ",
+			keepSpaces: true,
+			expected:   []string{" Hello,", " world!", " "},
+		},
+		{
+			name:          "whitespace",
+			text:          "Hello,  world!",
+			preTokenizers: []PreTokenizer{Whitespace()},
+			expected:      []string{"Hello,", "world!"},
+		},
+		{
+			name:          "punctuation",
+			text:          "See example... (twice)",
+			preTokenizers: []PreTokenizer{Punctuation()},
+			expected:      []string{"See", "example", ".", ".", ".", "(", "twice", ")"},
+		},
+		{
+			name:          "punctuation with spaces",
+			text:          "See  example.",
+			preTokenizers: []PreTokenizer{Punctuation()},
+			keepSpaces:    true,
+			expected:      []string{"See", "  example", "."},
+		},
+		{
+			name:          "digits",
+			text:          "year 2021th",
+			preTokenizers: []PreTokenizer{Digits()},
+			expected:      []string{"year", "2", "0", "2", "1", "th"},
+		},
+		{
+			name:          "cjk",
+			text:          "我爱Go语言 ok",
+			preTokenizers: []PreTokenizer{CJK()},
+			expected:      []string{"我", "爱", "Go", "语", "言", "ok"},
+		},
+		{
+			name:          "gpt2",
+			text:          "I'll pay $100.50 don't",
+			preTokenizers: []PreTokenizer{GPT2()},
+			expected:      []string{"I", "'ll", "pay", "$", "100", ".", "50", "don", "'t"},
+		},
+		{
+			name:          "sequence",
+			text:          "abc12,",
+			preTokenizers: []PreTokenizer{Punctuation(), Digits()},
+			expected:      []string{"abc", "1", "2", ","},
+		},
+		{
+			name:          "reserved tokens aren't split",
+			text:          "[a] [MASK]",
+			preTokenizers: []PreTokenizer{Punctuation()},
+			expected:      []string{"[", "a", "]", "[MASK]"},
+		},
+		{
+			name:          "reserved tokens keep spaces",
+			text:          "a, [MASK]",
+			preTokenizers: []PreTokenizer{Punctuation()},
+			keepSpaces:    true,
+			expected:      []string{"a", ",", " [MASK]"},
+		},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			actual := preTokenize(tc.text, tc.preTokenizers, special, tc.keepSpaces)
+
+			if !reflect.DeepEqual(tc.expected, actual) {
+				t.Errorf("Expected: %q\nGot: %q\n", tc.expected, actual)
+			}
+		})
+	}
+}
+
+func TestTrain_WithPreTokenizers(t *testing.T) {
+	corpus := "See example. Another example."
+
+	model, err := Train(context.Background(), strings.NewReader(corpus), WithPreTokenizers(Punctuation()))
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	tokens, err := model.Encode(strings.NewReader("example."))
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	expected := []string{
+		BeginOfSentence,
+		BeginOfWord + "example" + EndOfWord,
+		BeginOfWord + "." + EndOfWord,
+		EndOfSentence,
+	}
+
+	if !reflect.DeepEqual(expected, tokens) {
+		t.Errorf("Expected: %v\nGot: %v\n", expected, tokens)
+	}
+}
+
+func TestTrain_WithPreTokenizers_ByteLevel(t *testing.T) {
+	text := "Go 1.16, 世界!"
+
+	model, err := Train(context.Background(), strings.NewReader(text), WithByteLevel(), WithPreTokenizers(GPT2(), CJK()))
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	tokens, err := model.Encode(strings.NewReader(text))
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	decoded, err := model.Decode(tokens)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	if decoded != text {
+		t.Errorf("Expected: %q\nGot: %q\n", text, decoded)
+	}
+}
+
+func TestPreTokenizers_ExportImport(t *testing.T) {
+	RegisterPreTokenizer(PreTokenizerFunc("test_dashes", func(word string) []string {
+		return splitRunes(word, func(r rune) bool { return r == '-' })
+	}))
+
+	preTokenizers, err := lookupPreTokenizers([]string{"test_dashes"})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	model, err := Train(context.Background(), strings.NewReader("a-b"), WithPreTokenizers(preTokenizers...))
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	buf := bytes.NewBuffer(nil)
+	if err := Export(model, buf); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	imported, err := Import(buf)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	expected := []string{"test_dashes"}
+	if actual := preTokenizerNames(imported.preTokenizers); !reflect.DeepEqual(expected, actual) {
+		t.Errorf("Expected: %v\nGot: %v\n", expected, actual)
+	}
+
+	tokens, err := imported.Encode(strings.NewReader("b-a"))
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	if len(tokens) != 5 || tokens[2] != BeginOfWord+"-"+EndOfWord {
+		t.Errorf("Split word expected. Got: %v", tokens)
+	}
+}
+
+func TestImport_UnknownPreTokenizer(t *testing.T) {
+	_, err := Import(strings.NewReader(`{"max_token_length":3,"vocab":["foo"],"pre_tokenizers":["missing"]}`))
+	if err == nil {
+		t.Error("Error expected")
+	}
+}
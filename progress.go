@@ -0,0 +1,186 @@
+package bpe
+
+import (
+	"io"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+const defaultProgressInterval = time.Second
+
+// TrainPhase is a stage of training.
+type TrainPhase int
+
+const (
+	// PhaseCounting is reading of the source and counting of candidate tokens.
+	PhaseCounting TrainPhase = iota
+	// PhaseSelecting is selection of vocabulary from counted candidates.
+	PhaseSelecting
+	// PhaseMerging is learning of merges. It's skipped by substring frequency training.
+	PhaseMerging
+)
+
+func (p TrainPhase) String() string {
+	switch p {
+	case PhaseCounting:
+		return "counting"
+	case PhaseSelecting:
+		return "selecting"
+	case PhaseMerging:
+		return "merging"
+	}
+
+	return "unknown"
+}
+
+// TrainProgress is a snapshot of the training state.
+type TrainProgress struct {
+	Phase     TrainPhase
+	BytesRead int64
+	Sentences int64
+
+	// Candidates is the number of distinct candidate tokens: words for merges and substrings otherwise.
+	// It's an upper bound while several workers or sources are counted at once and exact afterwards.
+	Candidates int
+
+	// Merges is the number of merges learned so far.
+	Merges int
+
+	Elapsed time.Duration
+
+	// Done is set for the last report of successful training.
+	Done bool
+}
+
+// progressTracker collects training statistics and reports them not more often than once per interval.
+// Methods of nil tracker do nothing, so training code doesn't need to check whether progress is requested.
+type progressTracker struct {
+	// Counters are updated atomically by counting goroutines. Keep them first for 64-bit alignment.
+	bytesRead  int64
+	sentences  int64
+	candidates int64
+	merges     int64
+
+	report   func(TrainProgress)
+	interval time.Duration
+	start    time.Time
+
+	mu         sync.Mutex // Serializes reports.
+	phase      TrainPhase
+	lastReport time.Time
+}
+
+func newProgressTracker(report func(TrainProgress), interval time.Duration) *progressTracker {
+	if report == nil {
+		return nil
+	}
+
+	return &progressTracker{
+		report:   report,
+		interval: interval,
+		start:    time.Now(),
+	}
+}
+
+// setPhase switches training phase and reports it immediately.
+func (p *progressTracker) setPhase(phase TrainPhase) {
+	if p == nil {
+		return
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	p.phase = phase
+	p.send(false)
+}
+
+// counted records counted sentences and the growth of the frequency table.
+func (p *progressTracker) counted(sentences, newCandidates int) {
+	if p == nil {
+		return
+	}
+
+	atomic.AddInt64(&p.sentences, int64(sentences))
+	atomic.AddInt64(&p.candidates, int64(newCandidates))
+	p.tick()
+}
+
+// setCandidates replaces estimated number of candidates with the exact one.
+func (p *progressTracker) setCandidates(n int) {
+	if p == nil {
+		return
+	}
+
+	atomic.StoreInt64(&p.candidates, int64(n))
+}
+
+func (p *progressTracker) setMerges(n int) {
+	if p == nil {
+		return
+	}
+
+	atomic.StoreInt64(&p.merges, int64(n))
+	p.tick()
+}
+
+// finish sends the final report.
+func (p *progressTracker) finish() {
+	if p == nil {
+		return
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	p.send(true)
+}
+
+// tick reports progress if the interval has passed since the last report.
+func (p *progressTracker) tick() {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if time.Since(p.lastReport) < p.interval {
+		return
+	}
+
+	p.send(false)
+}
+
+// send must be called with locked mutex.
+func (p *progressTracker) send(done bool) {
+	p.lastReport = time.Now()
+
+	p.report(TrainProgress{
+		Phase:      p.phase,
+		BytesRead:  atomic.LoadInt64(&p.bytesRead),
+		Sentences:  atomic.LoadInt64(&p.sentences),
+		Candidates: int(atomic.LoadInt64(&p.candidates)),
+		Merges:     int(atomic.LoadInt64(&p.merges)),
+		Elapsed:    p.lastReport.Sub(p.start),
+		Done:       done,
+	})
+}
+
+// reader counts bytes read from r.
+func (p *progressTracker) reader(r io.Reader) io.Reader {
+	if p == nil {
+		return r
+	}
+
+	return &progressReader{r: r, progress: p}
+}
+
+type progressReader struct {
+	r        io.Reader
+	progress *progressTracker
+}
+
+func (r *progressReader) Read(p []byte) (int, error) {
+	n, err := r.r.Read(p)
+	atomic.AddInt64(&r.progress.bytesRead, int64(n))
+
+	return n, err
+}
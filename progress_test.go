@@ -0,0 +1,107 @@
+package bpe
+
+import (
+	"bytes"
+	"context"
+	"io/ioutil"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestTrain_WithProgress(t *testing.T) {
+	corpus, err := ioutil.ReadFile("example.txt")
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	tt := []struct {
+		name           string
+		options        []TrainOption
+		expectedPhases []TrainPhase
+	}{
+		{
+			name:           "merges",
+			options:        []TrainOption{WithMaxNumberOfTokens(300)},
+			expectedPhases: []TrainPhase{PhaseCounting, PhaseSelecting, PhaseMerging},
+		},
+		{
+			name:           "merges with workers",
+			options:        []TrainOption{WithMaxNumberOfTokens(300), WithWorkers(4)},
+			expectedPhases: []TrainPhase{PhaseCounting, PhaseSelecting, PhaseMerging},
+		},
+		{
+			name:           "substring frequency",
+			options:        []TrainOption{WithSubstringFrequency()},
+			expectedPhases: []TrainPhase{PhaseCounting, PhaseSelecting},
+		},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			var reports []TrainProgress
+
+			options := append(tc.options, WithProgressInterval(0), WithProgress(func(p TrainProgress) {
+				reports = append(reports, p)
+			}))
+
+			model, err := Train(context.Background(), bytes.NewReader(corpus), options...)
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+
+			var phases []TrainPhase
+			for i, report := range reports {
+				if len(phases) == 0 || phases[len(phases)-1] != report.Phase {
+					phases = append(phases, report.Phase)
+				}
+
+				if i > 0 && (report.BytesRead < reports[i-1].BytesRead || report.Sentences < reports[i-1].Sentences) {
+					t.Errorf("Counters must not decrease: %+v after %+v", report, reports[i-1])
+				}
+
+				if report.Done != (i == len(reports)-1) {
+					t.Errorf("Only the last report must be done. Report %d: %+v", i, report)
+				}
+			}
+
+			if !reflect.DeepEqual(tc.expectedPhases, phases) {
+				t.Errorf("Expected phases: %v\nGot: %v\n", tc.expectedPhases, phases)
+			}
+
+			last := reports[len(reports)-1]
+			if last.BytesRead != int64(len(corpus)) {
+				t.Errorf("Expected bytes read: %v\nGot: %v\n", len(corpus), last.BytesRead)
+			}
+
+			if last.Sentences == 0 || last.Candidates == 0 {
+				t.Errorf("Sentences and candidates expected to be counted: %+v", last)
+			}
+
+			if len(model.merges) != last.Merges {
+				t.Errorf("Expected merges: %v\nGot: %v\n", len(model.merges), last.Merges)
+			}
+		})
+	}
+}
+
+func TestTrain_WithProgressInterval(t *testing.T) {
+	var reports int
+
+	_, err := Train(
+		context.Background(),
+		bytes.NewReader(bytes.Repeat([]byte("Some sentence. "), 1000)),
+		WithProgressInterval(time.Hour),
+		WithProgress(func(TrainProgress) {
+			reports++
+		}),
+	)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	// Phase changes and the final report only.
+	if reports != 4 {
+		t.Errorf("Expected reports: %v\nGot: %v\n", 4, reports)
+	}
+}
@@ -0,0 +1,60 @@
+package bpe
+
+import (
+	"sort"
+)
+
+// WithMaxCandidates limits number of candidate tokens kept in memory during counting.
+// Once the limit is exceeded, the less frequent half of candidates is pruned the way Misra-Gries algorithm does it:
+// the median count is subtracted from all counts and candidates left without count are dropped.
+//
+// Counts are never overestimated and underestimated by at most 2*N/n, where N is the total number of counted
+// occurrences and n is the limit. So every candidate occurring more than 2*N/n times is kept.
+// Every worker and every source scanned at once get their own share of the limit,
+// so the bound grows proportionally to their number. Statistics of a source are pruned separately
+// before they are added to the counted ones, so up to twice the limit of candidates could be kept at once.
+// Zero means no limit which is the default.
+func WithMaxCandidates(n int) TrainOption {
+	return func(opts *trainOptions) {
+		opts.MaxCandidates = n
+	}
+}
+
+// pruneCandidates brings the size of the table below the limit if it's exceeded.
+// Subtracted median count is at least half of the table, so the error bound holds.
+func pruneCandidates(tft tokensFrequencyTable, limit int) {
+	if limit <= 0 || len(tft) <= limit {
+		return
+	}
+
+	counts := make([]int, 0, len(tft))
+	for _, count := range tft {
+		counts = append(counts, count)
+	}
+
+	sort.Ints(counts)
+	median := counts[len(counts)/2]
+
+	for token, count := range tft {
+		if count <= median {
+			delete(tft, token)
+			continue
+		}
+
+		tft[token] = count - median
+	}
+}
+
+// workerCandidatesLimit returns share of candidates limit of every worker.
+func workerCandidatesLimit(options *trainOptions) int {
+	if options.MaxCandidates <= 0 {
+		return 0
+	}
+
+	limit := options.MaxCandidates / options.Workers
+	if limit < 1 {
+		limit = 1
+	}
+
+	return limit
+}
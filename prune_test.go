@@ -0,0 +1,117 @@
+package bpe
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io/ioutil"
+	"reflect"
+	"testing"
+)
+
+func TestPruneCandidates(t *testing.T) {
+	tt := []struct {
+		name     string
+		tft      tokensFrequencyTable
+		limit    int
+		expected tokensFrequencyTable
+	}{
+		{
+			name:     "no limit",
+			tft:      tokensFrequencyTable{"a": 1, "b": 2, "c": 3},
+			limit:    0,
+			expected: tokensFrequencyTable{"a": 1, "b": 2, "c": 3},
+		},
+		{
+			name:     "under limit",
+			tft:      tokensFrequencyTable{"a": 1, "b": 2, "c": 3},
+			limit:    3,
+			expected: tokensFrequencyTable{"a": 1, "b": 2, "c": 3},
+		},
+		{
+			name:     "median is subtracted",
+			tft:      tokensFrequencyTable{"a": 1, "b": 2, "c": 5, "d": 10},
+			limit:    3,
+			expected: tokensFrequencyTable{"d": 5},
+		},
+		{
+			name:     "equal counts",
+			tft:      tokensFrequencyTable{"a": 1, "b": 1, "c": 1},
+			limit:    2,
+			expected: tokensFrequencyTable{},
+		},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			pruneCandidates(tc.tft, tc.limit)
+
+			if !reflect.DeepEqual(tc.expected, tc.tft) {
+				t.Errorf("Expected: %v\nGot: %v\n", tc.expected, tc.tft)
+			}
+		})
+	}
+}
+
+func TestCalculateTokensFrequency_WithMaxCandidates(t *testing.T) {
+	example, err := ioutil.ReadFile("example.txt")
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	corpus := bytes.Repeat(example, 20)
+
+	for _, substrings := range []bool{false, true} {
+		options := defaultTrainOptions()
+		options.SubstringFrequency = substrings
+
+		exact, err := calculateTokensFrequency(context.Background(), bytes.NewReader(corpus), options)
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+
+		var total int
+		for _, count := range exact {
+			total += count
+		}
+
+		limit := len(exact) / 4
+		options.MaxCandidates = limit
+
+		pruned, err := calculateTokensFrequency(context.Background(), bytes.NewReader(corpus), options)
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+
+		if len(pruned) > limit {
+			t.Errorf("Expected not more than %d candidates. Got: %d", limit, len(pruned))
+		}
+
+		maxError := 2 * total / limit
+
+		for token, count := range exact {
+			if pruned[token] > count || pruned[token] < count-maxError {
+				t.Errorf("Count of %q is out of bounds. Expected: %d-%d, got: %d", token, count-maxError, count, pruned[token])
+			}
+		}
+	}
+}
+
+func TestTrain_WithMaxCandidates(t *testing.T) {
+	// Frequent words mixed with unique noise.
+	corpus := bytes.NewBuffer(nil)
+	for i := 0; i < 2000; i++ {
+		fmt.Fprintf(corpus, "The cat and the dog %d. ", i)
+	}
+
+	for _, workers := range []int{1, 4} {
+		model, err := Train(context.Background(), bytes.NewReader(corpus.Bytes()), WithMaxCandidates(100), WithWorkers(workers))
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+
+		if _, ok := model.vocab[BeginOfWord+"the"+EndOfWord]; !ok {
+			t.Errorf("Workers %d: frequent word expected in vocabulary: %v", workers, model.vocab)
+		}
+	}
+}
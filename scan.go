@@ -1,40 +1,432 @@
 package bpe
 
 import (
+	"bufio"
+	"bytes"
+	"strings"
+	"sync"
 	"unicode"
 	"unicode/utf8"
+
+	"github.com/pkg/errors"
 )
 
-// Scan sentences.
-// Sentence starts from the beginning of string or from the previous sentence
-// and continues up to the EOF, end of line or .!? symbols with several heuristics.
-func scanSentences(data []byte, atEOF bool) (advance int, token []byte, err error) {
-	start := 0
+// SentenceSplitter splits text into sentences. Every sentence is encoded between
+// begin and end of sentence tokens. Name identifies splitter in exported model.
+type SentenceSplitter interface {
+	Name() string
 
-	// Skip leading spaces.
-	for pos, symbol := range string(data) {
-		if !unicode.IsSpace(symbol) {
-			break
+	// SplitSentences works like bufio.SplitFunc. Sentences must keep all spaces,
+	// so concatenation of the sentences gives the original text.
+	SplitSentences(data []byte, atEOF bool) (advance int, token []byte, err error)
+}
+
+// SentenceSplitterFunc makes sentence splitter from a split function. Custom splitters must be registered
+// with RegisterSentenceSplitter to import models which use them.
+func SentenceSplitterFunc(name string, split bufio.SplitFunc) SentenceSplitter {
+	return &sentenceSplitterFunc{name: name, split: split}
+}
+
+type sentenceSplitterFunc struct {
+	name  string
+	split bufio.SplitFunc
+}
+
+func (s *sentenceSplitterFunc) Name() string {
+	return s.name
+}
+
+func (s *sentenceSplitterFunc) SplitSentences(data []byte, atEOF bool) (advance int, token []byte, err error) {
+	return s.split(data, atEOF)
+}
+
+// HeuristicSplitter ends sentences at line breaks and at .!? symbols. It tries to keep
+// abbreviations, floats and numbers of list items inside of sentences. Abbreviations are guessed
+// by their length and capitalization. Use LanguageSplitter to recognize them with dictionaries.
+// It's the default splitter.
+func HeuristicSplitter() SentenceSplitter {
+	return &heuristicSplitter{name: "heuristic"}
+}
+
+// NewlineSplitter ends sentences at line breaks only. It suits corpora with a sentence per line.
+func NewlineSplitter() SentenceSplitter {
+	return SentenceSplitterFunc("newline", scanLines)
+}
+
+// NoSplitter treats the whole text as a single sentence. It suits code and logs.
+// Text must fit into the scan buffer.
+func NoSplitter() SentenceSplitter {
+	return SentenceSplitterFunc("none", scanAll)
+}
+
+var (
+	sentenceSplittersMu sync.RWMutex
+	sentenceSplitters   = map[string]SentenceSplitter{}
+)
+
+func init() {
+	for _, s := range []SentenceSplitter{HeuristicSplitter(), NewlineSplitter(), NoSplitter()} {
+		RegisterSentenceSplitter(s)
+	}
+}
+
+// RegisterSentenceSplitter makes sentence splitter available for import by its name.
+// Splitter with the same name is replaced.
+func RegisterSentenceSplitter(s SentenceSplitter) {
+	sentenceSplittersMu.Lock()
+	defer sentenceSplittersMu.Unlock()
+
+	sentenceSplitters[s.Name()] = s
+}
+
+// lookupSentenceSplitter returns registered sentence splitter by name. Empty name means the default one.
+func lookupSentenceSplitter(name string) (SentenceSplitter, error) {
+	if name == "" {
+		return nil, nil
+	}
+
+	sentenceSplittersMu.RLock()
+	s, ok := sentenceSplitters[name]
+	sentenceSplittersMu.RUnlock()
+
+	if ok {
+		return s, nil
+	}
+
+	if strings.HasPrefix(name, languageSplitterPrefix) {
+		return LanguageSplitter(strings.Split(name[len(languageSplitterPrefix):], ",")...)
+	}
+
+	return nil, errors.Errorf("unknown sentence splitter %q", name)
+}
+
+func sentenceSplitterName(s SentenceSplitter) string {
+	if s == nil {
+		return ""
+	}
+
+	return s.Name()
+}
+
+// sentenceSplitFunc returns split function of the splitter. Nil splitter means the default one.
+// Sentences start with spaces only if keepSpaces is set.
+func sentenceSplitFunc(s SentenceSplitter, keepSpaces bool) bufio.SplitFunc {
+	if s == nil {
+		s = HeuristicSplitter()
+	}
+
+	if keepSpaces {
+		return s.SplitSentences
+	}
+
+	return skipLeadingSpaces(s.SplitSentences)
+}
+
+// WithSentenceSplitter sets the way training text is split into sentences. Splitter is exported with the model
+// and used by Encode unless other one is set by WithEncodeSentenceSplitter.
+func WithSentenceSplitter(s SentenceSplitter) TrainOption {
+	return func(opts *trainOptions) {
+		opts.SentenceSplitter = s
+	}
+}
+
+// WithEncodeSentenceSplitter overrides sentence splitter of the model.
+func WithEncodeSentenceSplitter(s SentenceSplitter) EncodeOption {
+	return func(opts *encodeOptions) {
+		opts.SentenceSplitter = s
+	}
+}
+
+// skipLeadingSpaces wraps split function so sentences never start with spaces.
+// Text which contains only spaces isn't a sentence.
+func skipLeadingSpaces(split bufio.SplitFunc) bufio.SplitFunc {
+	return func(data []byte, atEOF bool) (int, []byte, error) {
+		start := len(data) - len(bytes.TrimLeftFunc(data, unicode.IsSpace))
+		if start == len(data) {
+			return start, nil, nil
+		}
+
+		advance, token, err := split(data[start:], atEOF)
+		if err != nil {
+			return 0, nil, err
 		}
 
-		start = pos
+		return start + advance, token, nil
+	}
+}
+
+// scanLines scans lines keeping line breaks.
+func scanLines(data []byte, atEOF bool) (advance int, token []byte, err error) {
+	if i := bytes.IndexByte(data, '\n'); i >= 0 {
+		return i + 1, data[:i+1], nil
+	}
+
+	return scanRest(data, atEOF)
+}
+
+// scanAll returns the whole text as a single token.
+func scanAll(data []byte, atEOF bool) (advance int, token []byte, err error) {
+	return scanRest(data, atEOF)
+}
+
+// scanRest returns the rest of data at EOF and requests more data otherwise.
+func scanRest(data []byte, atEOF bool) (advance int, token []byte, err error) {
+	// If we're at EOF, we have a final, non-empty, non-terminated sentence. Return it.
+	if atEOF && len(data) > 0 {
+		return len(data), data, nil
 	}
 
+	// Request more data.
+	return 0, nil, nil
+}
+
+// heuristicSplitter ends sentences at line breaks and at .!? symbols.
+type heuristicSplitter struct {
+	name          string
+	abbreviations map[string]struct{} // Lower case abbreviations without the last dot. Nil means guessing.
+}
+
+func (s *heuristicSplitter) Name() string {
+	return s.name
+}
+
+// SplitSentences scans sentences keeping all spaces.
+// Sentence starts from the beginning of string or from the previous sentence
+// and continues up to the EOF, end of line or .!? symbols with closing quotes and brackets after them.
+func (s *heuristicSplitter) SplitSentences(data []byte, atEOF bool) (advance int, token []byte, err error) {
 	// Scan until EOF, EOL or .!? symbol.
-	for width, i := 0, start; i < len(data); i += width {
+	for width, i := 0, 0; i < len(data); i += width {
 		var r rune
 		r, width = utf8.DecodeRune(data[i:])
 
-		if isEndOfSentence(r, data[start:i], data[i:]) {
-			return i + width, data[start : i+width], nil
+		// Whether punctuation ends sentence depends on the text after it, so it must be read first.
+		if !atEOF && strings.ContainsRune(".!?…", r) && !hasFollowingRune(data[i+width:]) {
+			return 0, nil, nil
+		}
+
+		if !s.isEndOfSentence(r, data[:i], data[i+width:]) {
+			continue
+		}
+
+		end := i + width
+		if r != '\r' && r != '\n' {
+			end += closingLength(data[end:])
 		}
+
+		return end, data[:end], nil
 	}
 
-	// If we're at EOF, we have a final, non-empty, non-terminated sentence. Return it.
-	if atEOF && len(data) > start {
-		return len(data), data[start:], nil
+	return scanRest(data, atEOF)
+}
+
+// isEndOfSentence checks some heuristics to understand whether the symbol is the end of sentence or not.
+// Prev is the text of sentence before the symbol and next is the text after it.
+func (s *heuristicSplitter) isEndOfSentence(symbol rune, prev, next []byte) bool {
+	if len(prev) == 0 {
+		return false
 	}
 
-	// Request more data.
-	return start, nil, nil
+	switch symbol {
+	case '\r', '\n':
+		return true
+	case '!', '?':
+		// The last one of "?!" ends sentence.
+		return !startsWithAny(next, "!?")
+	case '…':
+		return isEndOfEllipsis(next)
+	case '.':
+		return s.isEndOfSentenceDot(prev, next)
+	}
+
+	return false
+}
+
+// isEndOfSentenceDot checks whether dot ends sentence.
+// Sad but dot isn't explicit marker of the end of sentence.
+// It can be used for name or other abbreviation, float, number of list item or ellipsis.
+func (s *heuristicSplitter) isEndOfSentenceDot(prev, next []byte) bool {
+	// The last dot of ellipsis decides.
+	if startsWithAny(next, ".") {
+		return false
+	}
+
+	if bytes.HasSuffix(prev, []byte("..")) {
+		return isEndOfEllipsis(next)
+	}
+
+	// Dot inside of a word like "z.B." or "example.com".
+	nextRune, _ := utf8.DecodeRune(next)
+	if len(next) > 0 && unicode.IsLetter(nextRune) {
+		return false
+	}
+
+	prevRune, _ := utf8.DecodeLastRune(prev)
+
+	if unicode.IsDigit(prevRune) {
+		// Looks like it's a float number.
+		if unicode.IsDigit(nextRune) {
+			return false
+		}
+
+		return !isListItemNumber(prev, next)
+	}
+
+	if !unicode.IsLetter(prevRune) {
+		return true
+	}
+
+	word := lastWord(prev)
+	if isInitialism(word) {
+		return false
+	}
+
+	if s.abbreviations == nil {
+		return !guessAbbreviation(prev)
+	}
+
+	_, ok := s.abbreviations[strings.ToLower(word)]
+
+	return !ok
+}
+
+const abbreviationLength = 4
+
+// guessAbbreviation checks whether prev ends with an abbreviation.
+// If the last word has up to abbreviationLength letters and starts with capital letter
+// it looks like a Mrs., Dr. or other abbreviation.
+func guessAbbreviation(prev []byte) bool {
+	prevRune, width := utf8.DecodeLastRune(prev)
+	nextAfterCurrent := prevRune // Is needed to check letter capitalization after getting first space.
+	margin := width
+
+	// Let's find the space.
+	for i := 0; i < abbreviationLength; i++ {
+		if len(prev)-margin <= 0 {
+			return true
+		}
+
+		currentRune, currentWidth := utf8.DecodeLastRune(prev[:len(prev)-margin])
+
+		// We've found the space. Let's check that the next character after space is a capitalized letter.
+		if unicode.IsSpace(currentRune) {
+			return unicode.IsUpper(nextAfterCurrent)
+		}
+
+		// Is token is inside some group?
+		if strings.ContainsRune(openingSymbols, currentRune) {
+			return true
+		}
+
+		// If it's not letter it's not an abbreviation.
+		if !unicode.IsLetter(currentRune) {
+			return false
+		}
+
+		nextAfterCurrent = currentRune
+		margin += currentWidth
+	}
+
+	// If the last n characters was letters it's probably is the end of string.
+	return false
+}
+
+const (
+	openingSymbols = `[({"'«“‘„`
+	closingSymbols = `])}"'»”’`
+)
+
+// closingLength returns length of closing quotes and brackets at the start of data.
+func closingLength(data []byte) int {
+	length := 0
+
+	for length < len(data) {
+		r, width := utf8.DecodeRune(data[length:])
+		if !strings.ContainsRune(closingSymbols, r) {
+			break
+		}
+
+		length += width
+	}
+
+	return length
+}
+
+// followingRune returns the first rune after closing quotes and spaces.
+func followingRune(next []byte) (rune, bool) {
+	rest := bytes.TrimLeftFunc(next[closingLength(next):], unicode.IsSpace)
+	if len(rest) == 0 {
+		return 0, false
+	}
+
+	r, _ := utf8.DecodeRune(rest)
+
+	return r, true
+}
+
+// hasFollowingRune checks whether the whole rune after closing quotes and spaces is in next.
+func hasFollowingRune(next []byte) bool {
+	rest := bytes.TrimLeftFunc(next[closingLength(next):], unicode.IsSpace)
+
+	return utf8.FullRune(rest)
+}
+
+// isEndOfEllipsis checks whether ellipsis ends sentence. It does if the next sentence starts with capital letter.
+func isEndOfEllipsis(next []byte) bool {
+	following, ok := followingRune(next)
+
+	return !ok || unicode.IsUpper(following)
+}
+
+// isListItemNumber checks whether number before the dot starts the line and is followed by text like "1. First".
+func isListItemNumber(prev, next []byte) bool {
+	line := prev[bytes.LastIndexAny(prev, "\r\n")+1:]
+	number := bytes.TrimLeftFunc(line, unicode.IsSpace)
+
+	if len(number) > 3 || len(bytes.TrimLeftFunc(number, unicode.IsDigit)) > 0 {
+		return false
+	}
+
+	r, width := utf8.DecodeRune(next)
+	if r != ' ' && r != '\t' {
+		return false
+	}
+
+	_, ok := followingRune(next[width:])
+
+	return ok
+}
+
+// lastWord returns the last word of text without opening quotes and brackets.
+func lastWord(text []byte) string {
+	word := text[bytes.LastIndexFunc(text, unicode.IsSpace)+1:]
+
+	return strings.TrimLeft(string(word), openingSymbols)
+}
+
+// isInitialism checks whether word is an initial like "J" or an initialism like "U.S" or "e.g".
+func isInitialism(word string) bool {
+	parts := strings.Split(word, ".")
+	if len(parts) == 1 {
+		r, width := utf8.DecodeRuneInString(word)
+		return width == len(word) && unicode.IsUpper(r)
+	}
+
+	for _, part := range parts {
+		letters := utf8.RuneCountInString(part)
+		if letters == 0 || letters > 2 || strings.IndexFunc(part, isNotLetter) >= 0 {
+			return false
+		}
+	}
+
+	return true
+}
+
+func isNotLetter(r rune) bool {
+	return !unicode.IsLetter(r)
+}
+
+func startsWithAny(data []byte, chars string) bool {
+	r, _ := utf8.DecodeRune(data)
+
+	return len(data) > 0 && strings.ContainsRune(chars, r)
 }
@@ -1,7 +1,14 @@
 package bpe
 
 import (
+	"bufio"
+	"bytes"
+	"context"
+	"io"
+	"reflect"
+	"strings"
 	"testing"
+	"testing/iotest"
 )
 
 func TestIsEndOfSentence(t *testing.T) {
@@ -45,7 +52,13 @@ func TestIsEndOfSentence(t *testing.T) {
 			prev:     "1",
 			lastRune: '.',
 			next:     " First",
-			// Yeah. It's hard to understand is it a list item number or something like year et the end of string.
+			expected: false,
+		},
+		{
+			name:     "year at the end of sentence",
+			prev:     "It was in 1999",
+			lastRune: '.',
+			next:     " Then",
 			expected: true,
 		},
 		{
@@ -104,14 +117,218 @@ func TestIsEndOfSentence(t *testing.T) {
 			next:     " New sentence",
 			expected: true,
 		},
+		{
+			name:     "initialism",
+			prev:     "the U.S",
+			lastRune: '.',
+			next:     " Army",
+			expected: false,
+		},
+		{
+			name:     "lower case after dot",
+			prev:     "see e.g",
+			lastRune: '.',
+			next:     " this",
+			expected: false,
+		},
+		{
+			name:     "short word at the end",
+			prev:     "I ran",
+			lastRune: '.',
+			next:     " Then",
+			expected: true,
+		},
+		{
+			name:     "ellipsis inside",
+			prev:     "Wait..",
+			lastRune: '.',
+			next:     " what",
+			expected: false,
+		},
+		{
+			name:     "ellipsis at the end",
+			prev:     "Wait..",
+			lastRune: '.',
+			next:     " What",
+			expected: true,
+		},
+		{
+			name:     "not the last dot of ellipsis",
+			prev:     "Wait",
+			lastRune: '.',
+			next:     ".. What",
+			expected: false,
+		},
+		{
+			name:     "dot inside of word",
+			prev:     "see example",
+			lastRune: '.',
+			next:     "com",
+			expected: false,
+		},
+		{
+			name:     "?!",
+			prev:     "Really",
+			lastRune: '?',
+			next:     "! No",
+			expected: false,
+		},
 	}
 
 	for _, tc := range tt {
 		t.Run(tc.name, func(t *testing.T) {
-			actual := isEndOfSentence(tc.lastRune, []byte(tc.prev), []byte(tc.next))
+			actual := (&heuristicSplitter{}).isEndOfSentence(tc.lastRune, []byte(tc.prev), []byte(tc.next))
 			if tc.expected != actual {
 				t.Errorf("Expected: %v\nGot: %v\n", tc.expected, actual)
 			}
 		})
 	}
 }
+
+func TestSentenceSplitters(t *testing.T) {
+	text := "Dr. Smith came. He left!\nfunc main() { a.b() }\n"
+
+	tt := []struct {
+		splitter   SentenceSplitter
+		keepSpaces bool
+		expected   []string
+	}{
+		{
+			splitter: HeuristicSplitter(),
+			expected: []string{"Dr. Smith came.", "He left!", "func main() { a.b() }\n"},
+		},
+		{
+			splitter:   HeuristicSplitter(),
+			keepSpaces: true,
+			expected:   []string{"Dr. Smith came.", " He left!", "\nfunc main() { a.b() }\n"},
+		},
+		{
+			splitter: NewlineSplitter(),
+			expected: []string{"Dr. Smith came. He left!\n", "func main() { a.b() }\n"},
+		},
+		{
+			splitter: NoSplitter(),
+			expected: []string{text},
+		},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.splitter.Name(), func(t *testing.T) {
+			scanner := bufio.NewScanner(strings.NewReader(text))
+			scanner.Split(sentenceSplitFunc(tc.splitter, tc.keepSpaces))
+
+			var actual []string
+			for scanner.Scan() {
+				actual = append(actual, scanner.Text())
+			}
+
+			if !reflect.DeepEqual(tc.expected, actual) {
+				t.Errorf("Expected: %q\nGot: %q\n", tc.expected, actual)
+			}
+		})
+	}
+}
+
+func TestHeuristicSplitter_OneByteReader(t *testing.T) {
+	tt := []string{
+		"Pi is 3.14 today. See the U.S. Army now. Really?! Yes... no.",
+		"Wait… what? Wait… What?",
+		"1. First\n2. Second",
+		"He said \"Stop.\" Then left.",
+		"She quoted \u00abfin.\u00bb Puis partit.",
+		"Dr. Smith came.   \n He left!",
+		"Привет. Как дела?",
+	}
+
+	german, err := LanguageSplitter("de")
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	scan := func(r io.Reader, splitter SentenceSplitter) []string {
+		scanner := bufio.NewScanner(r)
+		scanner.Split(sentenceSplitFunc(splitter, false))
+
+		var sentences []string
+		for scanner.Scan() {
+			sentences = append(sentences, scanner.Text())
+		}
+
+		return sentences
+	}
+
+	for _, splitter := range []SentenceSplitter{HeuristicSplitter(), german} {
+		for _, text := range tt {
+			t.Run(splitter.Name()+"/"+text, func(t *testing.T) {
+				expected := scan(strings.NewReader(text), splitter)
+				actual := scan(iotest.OneByteReader(strings.NewReader(text)), splitter)
+
+				if !reflect.DeepEqual(expected, actual) {
+					t.Errorf("Expected: %q\nGot: %q\n", expected, actual)
+				}
+			})
+		}
+	}
+}
+
+func TestEncode_WithSentenceSplitter(t *testing.T) {
+	model, err := Train(context.Background(), strings.NewReader("a. b\nc"), WithSentenceSplitter(NoSplitter()))
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	buf := bytes.NewBuffer(nil)
+	if err := Export(model, buf); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	imported, err := Import(buf)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	dots := SentenceSplitterFunc("dots", func(data []byte, atEOF bool) (int, []byte, error) {
+		if i := bytes.IndexByte(data, '.'); i >= 0 {
+			return i + 1, data[:i+1], nil
+		}
+
+		return scanRest(data, atEOF)
+	})
+
+	tt := []struct {
+		name      string
+		options   []EncodeOption
+		sentences int
+	}{
+		{name: "model splitter", sentences: 1},
+		{name: "overridden splitter", options: []EncodeOption{WithEncodeSentenceSplitter(NewlineSplitter())}, sentences: 2},
+		{name: "custom splitter", options: []EncodeOption{WithEncodeSentenceSplitter(dots)}, sentences: 2},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			tokens, err := imported.Encode(strings.NewReader("a. b\nc"), tc.options...)
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+
+			var sentences int
+			for _, token := range tokens {
+				if token == BeginOfSentence {
+					sentences++
+				}
+			}
+
+			if sentences != tc.sentences {
+				t.Errorf("Expected %d sentences. Got: %v", tc.sentences, tokens)
+			}
+		})
+	}
+}
+
+func TestImport_UnknownSentenceSplitter(t *testing.T) {
+	_, err := Import(strings.NewReader(`{"max_token_length":3,"vocab":["foo"],"sentence_splitter":"missing"}`))
+	if err == nil {
+		t.Error("Error expected")
+	}
+}
@@ -0,0 +1,224 @@
+package bpe
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+
+	"github.com/pkg/errors"
+)
+
+// TrainFromFiles works like Train but learns vocabulary from several files.
+// Paths could be glob patterns like /data/*.txt. Every file is scanned separately,
+// so sentences never cross file boundaries. Up to WithWorkers files are scanned at once.
+func TrainFromFiles(ctx context.Context, paths []string, opts ...TrainOption) (*BPE, error) {
+	files, err := expandPaths(paths)
+	if err != nil {
+		return nil, err
+	}
+
+	sources := make([]trainSource, 0, len(files))
+
+	for _, path := range files {
+		path := path
+
+		sources = append(sources, trainSource{
+			name: "file " + path,
+			open: func() (io.ReadCloser, error) {
+				return os.Open(path)
+			},
+		})
+	}
+
+	return trainFromSources(ctx, sources, opts...)
+}
+
+// TrainFromReaders works like Train but learns vocabulary from several sources.
+// Every source is scanned separately, so sentences never cross source boundaries.
+// Up to WithWorkers sources are scanned at once.
+func TrainFromReaders(ctx context.Context, readers []io.Reader, opts ...TrainOption) (*BPE, error) {
+	sources := make([]trainSource, 0, len(readers))
+
+	for i, r := range readers {
+		r := r
+
+		sources = append(sources, trainSource{
+			name: fmt.Sprintf("source #%d", i),
+			open: func() (io.ReadCloser, error) {
+				return ioutil.NopCloser(r), nil
+			},
+		})
+	}
+
+	return trainFromSources(ctx, sources, opts...)
+}
+
+// trainSource is opened right before scanning to avoid holding all files open at once.
+type trainSource struct {
+	name string
+	open func() (io.ReadCloser, error)
+}
+
+func trainFromSources(ctx context.Context, sources []trainSource, opts ...TrainOption) (*BPE, error) {
+	options := defaultTrainOptions()
+	options.Apply(opts...)
+
+	if options.CheckpointWrite != nil {
+		return nil, errors.New("checkpoints aren't supported for multiple sources")
+	}
+
+	options.startProgress()
+
+	tft, err := calculateSourcesFrequency(ctx, sources, options)
+	if err != nil {
+		return nil, err
+	}
+
+	return newModelFromFrequencyTable(tft, options), nil
+}
+
+// calculateSourcesFrequency counts every source separately and merges the statistics.
+// Workers are shared between sources scanned at once.
+func calculateSourcesFrequency(
+	ctx context.Context,
+	sources []trainSource,
+	options *trainOptions,
+) (tokensFrequencyTable, error) {
+	concurrency := options.Workers
+	if concurrency > len(sources) {
+		concurrency = len(sources)
+	}
+
+	if concurrency < 1 {
+		return make(tokensFrequencyTable), nil
+	}
+
+	sourceOptions := sharedOptions(options, concurrency)
+
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	queue := make(chan trainSource)
+	tables := make([]tokensFrequencyTable, concurrency)
+	errs := make([]error, concurrency)
+	wg := sync.WaitGroup{}
+
+	for i := 0; i < concurrency; i++ {
+		tables[i] = make(tokensFrequencyTable)
+		wg.Add(1)
+
+		go func(i int) {
+			defer wg.Done()
+
+			for source := range queue {
+				if err := countSource(ctx, source, tables[i], sourceOptions); err != nil {
+					errs[i] = err
+					cancel()
+
+					return
+				}
+			}
+		}(i)
+	}
+
+sourcesLoop:
+	for _, source := range sources {
+		select {
+		case <-ctx.Done():
+			break sourcesLoop
+		case queue <- source:
+		}
+	}
+
+	close(queue)
+	wg.Wait()
+
+	// Report the real error instead of the cancellation caused by it.
+	for _, err := range errs {
+		if err != nil && err != context.Canceled {
+			return nil, err
+		}
+	}
+
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	tft := mergeTokensFrequencyTables(tables)
+	pruneCandidates(tft, options.MaxCandidates)
+
+	return tft, nil
+}
+
+// sharedOptions returns options of every of n sources scanned at once.
+// Workers and candidates limit are divided between them.
+func sharedOptions(options *trainOptions, n int) *trainOptions {
+	shared := *options
+	shared.Workers = options.Workers / n
+
+	if options.MaxCandidates > 0 {
+		shared.MaxCandidates = options.MaxCandidates / n
+		if shared.MaxCandidates < 1 {
+			shared.MaxCandidates = 1
+		}
+	}
+
+	return &shared
+}
+
+// countSource adds statistics of the source to the table.
+func countSource(ctx context.Context, source trainSource, tft tokensFrequencyTable, options *trainOptions) error {
+	r, err := source.open()
+	if err != nil {
+		return errors.Wrap(err, source.name)
+	}
+
+	defer r.Close()
+
+	sourceTable, err := calculateTokensFrequency(ctx, r, options)
+	if err != nil {
+		if err == context.Canceled || err == context.DeadlineExceeded {
+			return err
+		}
+
+		return errors.Wrap(err, source.name)
+	}
+
+	for token, count := range sourceTable {
+		tft[token] += count
+	}
+
+	pruneCandidates(tft, options.MaxCandidates)
+
+	return nil
+}
+
+// expandPaths replaces glob patterns with paths of matched files.
+func expandPaths(paths []string) ([]string, error) {
+	files := make([]string, 0, len(paths))
+
+	for _, path := range paths {
+		if !strings.ContainsAny(path, `*?[`) {
+			files = append(files, path)
+			continue
+		}
+
+		matches, err := filepath.Glob(path)
+		if err != nil {
+			return nil, errors.Wrapf(err, "pattern %s", path)
+		}
+
+		if len(matches) == 0 {
+			return nil, errors.Errorf("pattern %s: no files matched", path)
+		}
+
+		files = append(files, matches...)
+	}
+
+	return files, nil
+}
@@ -0,0 +1,221 @@
+package bpe
+
+import (
+	"context"
+	"io"
+	"io/ioutil"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+func TestTrainFromReaders(t *testing.T) {
+	tt := []struct {
+		name          string
+		sources       []string
+		expectedVocab []string
+	}{
+		{
+			name:          "sentences aren't glued across sources",
+			sources:       []string{"aaa", "bbb"},
+			expectedVocab: []string{"<w>aaa</w>", "<w>bbb</w>"},
+		},
+		{
+			name:          "statistics are merged",
+			sources:       []string{"ab ab", "ab cd"},
+			expectedVocab: []string{"<w>ab</w>"},
+		},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			for _, workers := range []int{1, 2, 4} {
+				readers := make([]io.Reader, 0, len(tc.sources))
+				for _, source := range tc.sources {
+					readers = append(readers, strings.NewReader(source))
+				}
+
+				model, err := TrainFromReaders(context.Background(), readers, WithWorkers(workers), WithMaxNumberOfTokens(50))
+				if err != nil {
+					t.Fatalf("Unexpected error: %v", err)
+				}
+
+				for _, token := range tc.expectedVocab {
+					if _, ok := model.vocab[token]; !ok {
+						t.Errorf("Workers %d: token %q expected in vocabulary %v", workers, token, model.vocab)
+					}
+				}
+			}
+		})
+	}
+}
+
+func TestTrainFromReaders_SameAsTrain(t *testing.T) {
+	corpus, err := ioutil.ReadFile("example.txt")
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	expected, err := Train(context.Background(), strings.NewReader(string(corpus)), WithMaxNumberOfTokens(300))
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	actual, err := TrainFromReaders(context.Background(), []io.Reader{strings.NewReader(string(corpus))}, WithMaxNumberOfTokens(300))
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	if !reflect.DeepEqual(expected.tokens, actual.tokens) {
+		t.Errorf("Expected: %v\nGot: %v\n", expected.tokens, actual.tokens)
+	}
+}
+
+func TestSharedOptions(t *testing.T) {
+	tt := []struct {
+		workers, candidates, sources int
+		expectedWorkers              int
+		expectedCandidates           int
+	}{
+		{workers: 4, candidates: 100, sources: 4, expectedWorkers: 1, expectedCandidates: 25},
+		{workers: 4, candidates: 100, sources: 2, expectedWorkers: 2, expectedCandidates: 50},
+		{workers: 4, candidates: 2, sources: 4, expectedWorkers: 1, expectedCandidates: 1},
+		{workers: 4, candidates: 0, sources: 4, expectedWorkers: 1, expectedCandidates: 0},
+	}
+
+	for _, tc := range tt {
+		options := defaultTrainOptions()
+		options.Workers = tc.workers
+		options.MaxCandidates = tc.candidates
+
+		shared := sharedOptions(options, tc.sources)
+		if shared.Workers != tc.expectedWorkers || shared.MaxCandidates != tc.expectedCandidates {
+			t.Errorf("Expected %d workers and %d candidates. Got: %d and %d",
+				tc.expectedWorkers, tc.expectedCandidates, shared.Workers, shared.MaxCandidates)
+		}
+	}
+}
+
+func TestTrainFromReaders_Error(t *testing.T) {
+	readers := []io.Reader{
+		strings.NewReader("foo bar"),
+		&failingReader{err: errors.New("broken disk")},
+	}
+
+	_, err := TrainFromReaders(context.Background(), readers, WithWorkers(2))
+	if err == nil {
+		t.Fatal("Error expected")
+	}
+
+	if !strings.Contains(err.Error(), "source #1") || !strings.Contains(err.Error(), "broken disk") {
+		t.Errorf("Error should point to the failed source. Got: %v", err)
+	}
+}
+
+func TestTrainFromReaders_WithTimeout(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	readers := []io.Reader{&endlessReader{data: []byte("foo. ")}, &endlessReader{data: []byte("bar. ")}}
+
+	_, err := TrainFromReaders(ctx, readers, WithWorkers(2))
+	if err != context.Canceled {
+		t.Errorf("Expected: %v\nGot: %v\n", context.Canceled, err)
+	}
+}
+
+func TestTrainFromFiles(t *testing.T) {
+	dir := t.TempDir()
+
+	for name, content := range map[string]string{
+		"a.txt": "lower lowest",
+		"b.txt": "newer newest",
+		"c.md":  "wider widest",
+	} {
+		if err := ioutil.WriteFile(filepath.Join(dir, name), []byte(content), 0600); err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+	}
+
+	model, err := TrainFromFiles(context.Background(), []string{filepath.Join(dir, "*.txt")}, WithWorkers(2))
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	for _, symbol := range []string{"l", "n"} {
+		if _, ok := model.vocab[symbol]; !ok && !hasTokenWith(model, symbol) {
+			t.Errorf("Symbol %q of matched files expected in vocabulary %v", symbol, model.vocab)
+		}
+	}
+
+	if hasTokenWith(model, "i") {
+		t.Errorf("Symbols of not matched files aren't expected in vocabulary %v", model.vocab)
+	}
+}
+
+func TestTrainFromFiles_Errors(t *testing.T) {
+	dir := t.TempDir()
+
+	tt := []struct {
+		name     string
+		paths    []string
+		expected string
+	}{
+		{
+			name:     "missing file",
+			paths:    []string{filepath.Join(dir, "missing.txt")},
+			expected: "missing.txt",
+		},
+		{
+			name:     "no matches",
+			paths:    []string{filepath.Join(dir, "*.txt")},
+			expected: "no files matched",
+		},
+		{
+			name:     "bad pattern",
+			paths:    []string{filepath.Join(dir, "[")},
+			expected: "pattern",
+		},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			_, err := TrainFromFiles(context.Background(), tc.paths)
+			if err == nil || !strings.Contains(err.Error(), tc.expected) {
+				t.Errorf("Expected error with %q. Got: %v", tc.expected, err)
+			}
+		})
+	}
+}
+
+func hasTokenWith(model *BPE, symbol string) bool {
+	for token := range model.vocab {
+		if strings.Contains(token, symbol) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// failingReader always returns the error.
+type failingReader struct {
+	err error
+}
+
+func (r *failingReader) Read([]byte) (int, error) {
+	return 0, r.err
+}
+
+func TestTrainFromReaders_WithCheckpoint(t *testing.T) {
+	readers := []io.Reader{strings.NewReader("foo")}
+
+	_, err := TrainFromReaders(context.Background(), readers, WithCheckpoint(ioutil.Discard, time.Minute))
+	if err == nil {
+		t.Error("Error expected")
+	}
+}
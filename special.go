@@ -0,0 +1,128 @@
+package bpe
+
+import "sort"
+
+// SpecialTokens are tokens with special meaning. They are never split and have fixed IDs.
+// Empty fields are replaced with the default values.
+type SpecialTokens struct {
+	BeginOfWord     string `json:"begin_of_word"`
+	EndOfWord       string `json:"end_of_word"`
+	BeginOfSentence string `json:"begin_of_sentence"`
+	EndOfSentence   string `json:"end_of_sentence"`
+	Unknown         string `json:"unknown"`
+
+	// Reserved are extra tokens like <pad>, <mask>, <sep> or <cls>.
+	// They are emitted as is when found in the text and are ignored in training.
+	Reserved []string `json:"reserved,omitempty"`
+}
+
+// DefaultSpecialTokens returns special tokens used by models by default.
+func DefaultSpecialTokens() SpecialTokens {
+	return SpecialTokens{
+		BeginOfWord:     BeginOfWord,
+		EndOfWord:       EndOfWord,
+		BeginOfSentence: BeginOfSentence,
+		EndOfSentence:   EndOfSentence,
+		Unknown:         UnknownToken,
+	}
+}
+
+// withDefaults returns copy of special tokens with empty fields set to the default values.
+func (s SpecialTokens) withDefaults() SpecialTokens {
+	defaults := DefaultSpecialTokens()
+
+	if s.BeginOfWord == "" {
+		s.BeginOfWord = defaults.BeginOfWord
+	}
+
+	if s.EndOfWord == "" {
+		s.EndOfWord = defaults.EndOfWord
+	}
+
+	if s.BeginOfSentence == "" {
+		s.BeginOfSentence = defaults.BeginOfSentence
+	}
+
+	if s.EndOfSentence == "" {
+		s.EndOfSentence = defaults.EndOfSentence
+	}
+
+	if s.Unknown == "" {
+		s.Unknown = defaults.Unknown
+	}
+
+	return s
+}
+
+// isDefault checks whether special tokens are the same as default ones.
+func (s SpecialTokens) isDefault() bool {
+	defaults := DefaultSpecialTokens()
+
+	return s.BeginOfWord == defaults.BeginOfWord &&
+		s.EndOfWord == defaults.EndOfWord &&
+		s.BeginOfSentence == defaults.BeginOfSentence &&
+		s.EndOfSentence == defaults.EndOfSentence &&
+		s.Unknown == defaults.Unknown &&
+		len(s.Reserved) == 0
+}
+
+// tokens returns all special tokens in order of their IDs.
+func (s SpecialTokens) tokens() []string {
+	tokens := []string{s.Unknown, s.BeginOfSentence, s.EndOfSentence, s.BeginOfWord, s.EndOfWord}
+
+	return append(tokens, s.Reserved...)
+}
+
+// reservedTokens returns special tokens followed by byte tokens.
+// Vocabulary tokens get IDs right after them.
+func (s SpecialTokens) reservedTokens() []string {
+	return append(s.tokens(), byteTokens...)
+}
+
+// isSpecial checks whether token is one of the special tokens.
+func (s SpecialTokens) isSpecial(token string) bool {
+	for _, special := range s.tokens() {
+		if token == special {
+			return true
+		}
+	}
+
+	return false
+}
+
+// isReserved checks whether token is one of the reserved tokens.
+func (s SpecialTokens) isReserved(token string) bool {
+	for _, reserved := range s.Reserved {
+		if token == reserved {
+			return true
+		}
+	}
+
+	return false
+}
+
+// specialTokens returns special tokens of the model.
+func (b *BPE) specialTokens() SpecialTokens {
+	return b.special.withDefaults()
+}
+
+// addedTokens returns reserved tokens as atomic tokens which are found anywhere in the text.
+// The longest tokens go first.
+func (s SpecialTokens) addedTokens() []AddedToken {
+	if len(s.Reserved) == 0 {
+		return nil
+	}
+
+	tokens := make([]AddedToken, 0, len(s.Reserved))
+	for _, token := range s.Reserved {
+		if token != "" {
+			tokens = append(tokens, AddedToken{Content: token})
+		}
+	}
+
+	sort.SliceStable(tokens, func(i, j int) bool {
+		return len(tokens[i].Content) > len(tokens[j].Content)
+	})
+
+	return tokens
+}
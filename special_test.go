@@ -0,0 +1,179 @@
+package bpe
+
+import (
+	"bytes"
+	"context"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestSpecialTokens_withDefaults(t *testing.T) {
+	actual := SpecialTokens{BeginOfWord: "▁", Reserved: []string{"<pad>"}}.withDefaults()
+	expected := SpecialTokens{
+		BeginOfWord:     "▁",
+		EndOfWord:       EndOfWord,
+		BeginOfSentence: BeginOfSentence,
+		EndOfSentence:   EndOfSentence,
+		Unknown:         UnknownToken,
+		Reserved:        []string{"<pad>"},
+	}
+
+	if !reflect.DeepEqual(expected, actual) {
+		t.Errorf("Expected: %v\nGot: %v\n", expected, actual)
+	}
+}
+
+func TestTrain_SpecialTokens(t *testing.T) {
+	special := SpecialTokens{
+		BeginOfWord:     "[w]",
+		EndOfWord:       "[/w]",
+		BeginOfSentence: "[s]",
+		EndOfSentence:   "[/s]",
+		Unknown:         "[unk]",
+	}
+
+	model, err := Train(
+		context.Background(),
+		strings.NewReader("foo <mask> foo"),
+		WithSpecialTokens(special),
+		WithReservedTokens("<pad>", "<mask>"),
+	)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	expectedVocab := map[string]struct{}{
+		"[w]f":       {},
+		"o":          {},
+		"o[/w]":      {},
+		"[w]fo":      {},
+		"[w]foo[/w]": {},
+	}
+	if !reflect.DeepEqual(expectedVocab, model.vocab) {
+		t.Errorf("Reserved tokens must not be learned.\nExpected: %v\nGot: %v\n", expectedVocab, model.vocab)
+	}
+
+	for id, token := range []string{"[unk]", "[s]", "[/s]", "[w]", "[/w]", "<pad>", "<mask>", "<0x00>"} {
+		if actual, ok := model.TokenToID(token); !ok || actual != id {
+			t.Errorf("Token %q expected to have ID %d. Got: %d", token, id, actual)
+		}
+	}
+
+	tokens, err := model.Encode(strings.NewReader("<mask> foo bar"))
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	expectedTokens := []string{"[s]", "<mask>", "[w]foo[/w]", "[unk]", "[unk]", "[unk]", "[/s]"}
+	if !reflect.DeepEqual(expectedTokens, tokens) {
+		t.Errorf("Expected: %v\nGot: %v\n", expectedTokens, tokens)
+	}
+
+	decoded, err := model.Decode([]string{"[s]", "<mask>", "[w]fo", "o[/w]", "[/s]"})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	if decoded != "<mask> foo" {
+		t.Errorf("Expected: %v\nGot: %v\n", "<mask> foo", decoded)
+	}
+
+	buf := bytes.NewBuffer(nil)
+	if err := Export(model, buf); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	imported, err := Import(buf)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	if !reflect.DeepEqual(model, imported) {
+		t.Errorf("Expected: %v\nGot: %v\n", model, imported)
+	}
+}
+
+func TestBPE_Encode_ReservedTokensByteLevel(t *testing.T) {
+	text := "foo <pad><pad> <pad>"
+
+	model, err := Train(context.Background(), strings.NewReader(text), WithByteLevel(), WithReservedTokens("<pad>"))
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	tokens, err := model.Encode(strings.NewReader(text))
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	// Reserved tokens are found in the raw text, so spaces before them are encoded separately.
+	expected := []string{BeginOfSentence, "foo", "Ġ", "<pad>", "<pad>", "Ġ", "<pad>", EndOfSentence}
+	if !reflect.DeepEqual(expected, tokens) {
+		t.Errorf("Expected: %v\nGot: %v\n", expected, tokens)
+	}
+
+	decoded, err := model.Decode(tokens)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	if decoded != text {
+		t.Errorf("Expected: %v\nGot: %v\n", text, decoded)
+	}
+}
+
+func TestBPE_Encode_ReservedTokensInText(t *testing.T) {
+	w := func(word string) string { return BeginOfWord + word + EndOfWord }
+
+	special := DefaultSpecialTokens()
+	special.Reserved = []string{"<sep>", "<mask>"}
+
+	model := newModelWithSpecialTokens([]string{w("hello"), w("world"), w("."), w(",")}, special)
+
+	tt := []struct {
+		text     string
+		expected []string
+	}{
+		{
+			text:     "hello<sep>world",
+			expected: []string{BeginOfSentence, w("hello"), "<sep>", w("world"), EndOfSentence},
+		},
+		{
+			text:     "hello <mask>.",
+			expected: []string{BeginOfSentence, w("hello"), "<mask>", w("."), EndOfSentence},
+		},
+		{
+			text:     "<sep>, world",
+			expected: []string{BeginOfSentence, "<sep>", w(","), w("world"), EndOfSentence},
+		},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.text, func(t *testing.T) {
+			tokens, err := model.Encode(strings.NewReader(tc.text))
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+
+			if !reflect.DeepEqual(tc.expected, tokens) {
+				t.Errorf("Expected: %v\nGot: %v\n", tc.expected, tokens)
+			}
+		})
+	}
+}
+
+func TestTrain_ReservedTokensInText(t *testing.T) {
+	model, err := Train(context.Background(), strings.NewReader("a<sep>b"), WithReservedTokens("<sep>"))
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	expected := map[string]struct{}{
+		BeginOfWord + "a" + EndOfWord: {},
+		BeginOfWord + "b" + EndOfWord: {},
+	}
+	if !reflect.DeepEqual(expected, model.vocab) {
+		t.Errorf("Reserved tokens must be cut out of words.\nExpected: %v\nGot: %v\n", expected, model.vocab)
+	}
+}
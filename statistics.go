@@ -0,0 +1,181 @@
+package bpe
+
+import (
+	"context"
+	"io"
+	"reflect"
+
+	"github.com/pkg/errors"
+)
+
+// trainingStatistics is what model has been trained on. It's enough to continue training.
+type trainingStatistics struct {
+	Options     savedTrainOptions    `json:"options"`
+	Frequencies tokensFrequencyTable `json:"frequencies"`
+}
+
+// savedTrainOptions are training options which are kept with statistics.
+type savedTrainOptions struct {
+	MaxNumberOfTokens  int           `json:"max_number_of_tokens"`
+	MaxTokenLength     int           `json:"max_token_length"`
+	MinFrequency       int           `json:"min_frequency,omitempty"`
+	LimitAlphabet      int           `json:"limit_alphabet,omitempty"`
+	InitialAlphabet    string        `json:"initial_alphabet,omitempty"`
+	ScanBufferSize     int           `json:"scan_buffer_size"`
+	WordsOnly          bool          `json:"words_only,omitempty"`
+	SubstringFrequency bool          `json:"substring_frequency,omitempty"`
+	ByteLevel          bool          `json:"byte_level,omitempty"`
+	SpecialTokens      SpecialTokens `json:"special_tokens"`
+	Normalizers        []string      `json:"normalizers,omitempty"`
+	PreTokenizers      []string      `json:"pre_tokenizers,omitempty"`
+	SentenceSplitter   string        `json:"sentence_splitter,omitempty"`
+}
+
+func newSavedTrainOptions(options *trainOptions) savedTrainOptions {
+	return savedTrainOptions{
+		MaxNumberOfTokens:  options.MaxNumberOfTokens,
+		MaxTokenLength:     options.MaxTokenLength,
+		MinFrequency:       options.MinFrequency,
+		LimitAlphabet:      options.LimitAlphabet,
+		InitialAlphabet:    options.InitialAlphabet,
+		ScanBufferSize:     options.ScanBufferSize,
+		WordsOnly:          options.WordsOnly,
+		SubstringFrequency: options.SubstringFrequency,
+		ByteLevel:          options.ByteLevel,
+		SpecialTokens:      options.SpecialTokens,
+		Normalizers:        normalizerNames(options.Normalizers),
+		PreTokenizers:      preTokenizerNames(options.PreTokenizers),
+		SentenceSplitter:   sentenceSplitterName(options.SentenceSplitter),
+	}
+}
+
+// Apply restores saved options. Normalizers, pre-tokenizers and sentence splitter must be registered.
+func (o savedTrainOptions) Apply(options *trainOptions) error {
+	normalizers, err := lookupNormalizers(o.Normalizers)
+	if err != nil {
+		return err
+	}
+
+	preTokenizers, err := lookupPreTokenizers(o.PreTokenizers)
+	if err != nil {
+		return err
+	}
+
+	splitter, err := lookupSentenceSplitter(o.SentenceSplitter)
+	if err != nil {
+		return err
+	}
+
+	options.MaxNumberOfTokens = o.MaxNumberOfTokens
+	options.MaxTokenLength = o.MaxTokenLength
+	options.MinFrequency = o.MinFrequency
+	options.LimitAlphabet = o.LimitAlphabet
+	options.InitialAlphabet = o.InitialAlphabet
+	options.ScanBufferSize = o.ScanBufferSize
+	options.WordsOnly = o.WordsOnly
+	options.SubstringFrequency = o.SubstringFrequency
+	options.ByteLevel = o.ByteLevel
+	options.SpecialTokens = o.SpecialTokens
+	options.Normalizers = normalizers
+	options.PreTokenizers = preTokenizers
+	options.SentenceSplitter = splitter
+
+	return nil
+}
+
+// sameCounting checks that statistics counted with both options are compatible.
+func (o savedTrainOptions) sameCounting(other savedTrainOptions) bool {
+	if o.SubstringFrequency && o.MaxTokenLength != other.MaxTokenLength {
+		return false
+	}
+
+	return o.WordsOnly == other.WordsOnly &&
+		o.SubstringFrequency == other.SubstringFrequency &&
+		o.ByteLevel == other.ByteLevel &&
+		reflect.DeepEqual(o.SpecialTokens, other.SpecialTokens) &&
+		reflect.DeepEqual(o.Normalizers, other.Normalizers) &&
+		reflect.DeepEqual(o.PreTokenizers, other.PreTokenizers) &&
+		o.SentenceSplitter == other.SentenceSplitter
+}
+
+// WithStatistics makes model keep statistics it has been trained on, so it could be trained further
+// with ContinueTraining. Statistics are exported together with the model. It makes model bigger.
+func WithStatistics() TrainOption {
+	return func(opts *trainOptions) {
+		opts.KeepStatistics = true
+	}
+}
+
+// ContinueTraining updates model with statistics of the new text. Existing tokens, merges and IDs are kept,
+// new tokens get IDs after all existing ones. WithMaxNumberOfTokens sets the new limit of the vocabulary size,
+// it's the same as in the previous training by default. Options which affect counting are taken from the model
+// and can't be changed. Model must be trained WithStatistics. It must not be called concurrently with encoding.
+func (b *BPE) ContinueTraining(ctx context.Context, r io.Reader, opts ...TrainOption) error {
+	if b.statistics == nil {
+		return errors.New("model has no training statistics")
+	}
+
+	options := defaultTrainOptions()
+	if err := b.statistics.Options.Apply(options); err != nil {
+		return err
+	}
+
+	options.Apply(opts...)
+
+	if !b.statistics.Options.sameCounting(newSavedTrainOptions(options)) {
+		return errors.New("counting options differ from the model statistics")
+	}
+
+	options.startProgress()
+
+	tft, err := calculateTokensFrequency(ctx, r, options)
+	if err != nil {
+		return err
+	}
+
+	tft = mergeTokensFrequencyTables([]tokensFrequencyTable{b.statistics.Frequencies, tft})
+	pruneCandidates(tft, options.MaxCandidates)
+
+	options.progress.setCandidates(len(tft))
+	options.progress.setPhase(PhaseSelecting)
+	defer options.progress.finish()
+
+	if options.SubstringFrequency && !options.ByteLevel {
+		b.extendVocab(substringVocab(tft, options, b.vocab))
+	} else {
+		vocab, merges := b.continueMerges(tft, options)
+		b.extendVocab(vocab)
+		b.merges = newMergesTable(merges)
+	}
+
+	b.statistics = &trainingStatistics{
+		Options:     newSavedTrainOptions(options),
+		Frequencies: tft,
+	}
+
+	return nil
+}
+
+// extendVocab adds new tokens to the vocabulary. They get IDs after all existing tokens.
+func (b *BPE) extendVocab(tokens []string) {
+	if b.ids == nil {
+		b.assignIDs(b.orderedVocab())
+	}
+
+	if b.vocab == nil {
+		b.vocab = make(map[string]struct{}, len(tokens))
+	}
+
+	for _, token := range tokens {
+		if _, ok := b.vocab[token]; ok {
+			continue
+		}
+
+		b.vocab[token] = struct{}{}
+		b.addID(token)
+
+		if len(token) > b.maxTokenLength {
+			b.maxTokenLength = len(token)
+		}
+	}
+}
@@ -0,0 +1,151 @@
+package bpe
+
+import (
+	"bytes"
+	"context"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestBPE_ContinueTraining(t *testing.T) {
+	tt := []struct {
+		name    string
+		options []TrainOption
+	}{
+		{
+			name: "merges",
+		},
+		{
+			name:    "byte level",
+			options: []TrainOption{WithByteLevel()},
+		},
+		{
+			name:    "substring frequency",
+			options: []TrainOption{WithSubstringFrequency()},
+		},
+	}
+
+	oldText := "low lower lowest low lower lowest"
+	newText := "жук жужжит жуку жуки жук"
+
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			options := append(tc.options, WithMaxNumberOfTokens(280), WithStatistics())
+
+			model, err := Train(context.Background(), strings.NewReader(oldText), options...)
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+
+			model.AddTokens(AddedToken{Content: "[TAG]"})
+
+			oldTokens := append([]string(nil), model.tokens...)
+			oldMerges := model.orderedMerges()
+			oldEncoded, _ := model.Encode(strings.NewReader(newText))
+
+			err = model.ContinueTraining(context.Background(), strings.NewReader(newText), WithMaxNumberOfTokens(320))
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+
+			if len(model.tokens) <= len(oldTokens) {
+				t.Fatalf("New tokens expected. Got: %v", model.tokens[len(oldTokens):])
+			}
+
+			if !reflect.DeepEqual(oldTokens, model.tokens[:len(oldTokens)]) {
+				t.Errorf("IDs of existing tokens must be kept.\nExpected: %v\nGot: %v\n", oldTokens, model.tokens[:len(oldTokens)])
+			}
+
+			if merges := model.orderedMerges(); !reflect.DeepEqual(oldMerges, merges[:len(oldMerges)]) {
+				t.Errorf("Existing merges must be kept.\nExpected: %v\nGot: %v\n", oldMerges, merges[:len(oldMerges)])
+			}
+
+			newEncoded, _ := model.Encode(strings.NewReader(newText))
+			if len(newEncoded) >= len(oldEncoded) {
+				t.Errorf("New text expected to be encoded shorter.\nBefore: %v\nAfter: %v\n", oldEncoded, newEncoded)
+			}
+
+			if model.statistics.Frequencies[strings.Fields(newText)[0]] == 0 && !model.statistics.Options.SubstringFrequency {
+				t.Errorf("Statistics of the new text expected to be kept: %v", model.statistics.Frequencies)
+			}
+		})
+	}
+}
+
+func TestBPE_ContinueTraining_Errors(t *testing.T) {
+	withoutStatistics, err := Train(context.Background(), strings.NewReader("foo bar"))
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	withStatistics, err := Train(context.Background(), strings.NewReader("foo bar"), WithStatistics())
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	tt := []struct {
+		name    string
+		model   *BPE
+		options []TrainOption
+	}{
+		{
+			name:  "no statistics",
+			model: withoutStatistics,
+		},
+		{
+			name:    "counting options differ",
+			model:   withStatistics,
+			options: []TrainOption{WithWordsOnly()},
+		},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			if err := tc.model.ContinueTraining(context.Background(), strings.NewReader("baz"), tc.options...); err == nil {
+				t.Error("Error expected")
+			}
+		})
+	}
+}
+
+func TestStatistics_ExportImport(t *testing.T) {
+	model, err := Train(context.Background(), strings.NewReader("low lower lowest"), WithStatistics(), WithMaxNumberOfTokens(20))
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	// Added token ends up between vocabulary tokens.
+	model.AddTokens(AddedToken{Content: "[TAG]", SingleWord: true})
+
+	err = model.ContinueTraining(context.Background(), strings.NewReader("newer newest"), WithMaxNumberOfTokens(40))
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	buf := bytes.NewBuffer(nil)
+	if err := Export(model, buf); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	imported, err := Import(buf)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	if !reflect.DeepEqual(model.tokens, imported.tokens) {
+		t.Errorf("Expected: %v\nGot: %v\n", model.tokens, imported.tokens)
+	}
+
+	if !reflect.DeepEqual(model.statistics, imported.statistics) {
+		t.Errorf("Expected: %v\nGot: %v\n", model.statistics, imported.statistics)
+	}
+
+	if !reflect.DeepEqual(model.added, imported.added) {
+		t.Errorf("Expected: %v\nGot: %v\n", model.added, imported.added)
+	}
+
+	if err := imported.ContinueTraining(context.Background(), strings.NewReader("wider widest")); err != nil {
+		t.Errorf("Unexpected error: %v", err)
+	}
+}
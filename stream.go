@@ -0,0 +1,111 @@
+package bpe
+
+import (
+	"bufio"
+	"context"
+	"io"
+
+	"github.com/pkg/errors"
+)
+
+// TokenStream reads tokens of text sentence by sentence, so only tokens of the current sentence are kept in memory.
+// It's used like bufio.Scanner:
+//
+//	stream := model.EncodeStream(ctx, r)
+//	for stream.Next() {
+//		fmt.Println(stream.Token())
+//	}
+//
+//	if err := stream.Err(); err != nil {
+//		...
+//	}
+type TokenStream struct {
+	ctx     context.Context
+	model   *BPE
+	options *encodeOptions
+	scanner *bufio.Scanner
+	tokens  *encoded // Tokens of the current sentence.
+	next    int      // Index of the next token of the current sentence.
+	token   string
+	err     error
+}
+
+// EncodeStream returns stream of tokens of text from r. Context is checked before every sentence,
+// so the stream stops after the current sentence is read once the context is done.
+// The whole sentence is kept in memory while it's encoded, so sentences longer than
+// WithEncodeScanBufferSize stop the stream with an error. Use a bigger buffer or another sentence splitter
+// for text without sentence boundaries. Truncation and padding need the whole encoding, so they aren't supported.
+func (b *BPE) EncodeStream(ctx context.Context, r io.Reader, opts ...EncodeOption) *TokenStream {
+	options := defaultEncodeOptions()
+	options.Apply(opts...)
+
+	stream := &TokenStream{
+		ctx:     ctx,
+		model:   b,
+		options: options,
+		scanner: b.sentenceScanner(r, options, false, nil),
+		tokens:  &encoded{tokens: make([]string, 0, defaultTokensCap)},
+	}
+
+	if options.fixedLength() {
+		stream.err = errors.New("truncation and padding aren't supported by token stream")
+	}
+
+	return stream
+}
+
+// Next advances the stream to the next token which is available with Token.
+// It returns false when the stream is over or an error occurs.
+func (s *TokenStream) Next() bool {
+	for s.err == nil && s.next == len(s.tokens.tokens) {
+		if err := s.ctx.Err(); err != nil {
+			s.err = err
+
+			break
+		}
+
+		if !s.scanner.Scan() {
+			if err := s.scanner.Err(); err != nil && err != io.EOF {
+				s.err = errors.Wrap(err, "file scan")
+			}
+
+			break
+		}
+
+		s.tokens.tokens = s.tokens.tokens[:0]
+		s.next = 0
+		s.model.encodeSentence(s.tokens, s.scanner.Text(), s.options)
+		s.tokens.sentence++
+	}
+
+	if s.err != nil || s.next == len(s.tokens.tokens) {
+		s.token = ""
+
+		return false
+	}
+
+	s.token = s.tokens.tokens[s.next]
+	s.next++
+
+	return true
+}
+
+// Token returns the current token.
+func (s *TokenStream) Token() string {
+	return s.token
+}
+
+// ID returns ID of the current token. Tokens out of vocabulary get ID of unknown token.
+func (s *TokenStream) ID() int {
+	id, ok := s.model.TokenToID(s.token)
+	if !ok {
+		id, _ = s.model.TokenToID(s.model.specialTokens().Unknown)
+	}
+
+	return id
+}
+
+// Err returns the first error of the stream.
+func (s *TokenStream) Err() error {
+	return s.err
+}
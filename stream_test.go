@@ -0,0 +1,157 @@
+package bpe
+
+import (
+	"context"
+	"io/ioutil"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestBPE_EncodeStream(t *testing.T) {
+	corpus, err := ioutil.ReadFile("example.txt")
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	model, err := Train(context.Background(), strings.NewReader(string(corpus)))
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	tt := []struct {
+		name    string
+		text    string
+		options []EncodeOption
+	}{
+		{name: "empty", text: ""},
+		{name: "corpus", text: string(corpus)},
+		{name: "collapsed unknowns", text: "Hello, world! Как дела?", options: []EncodeOption{WithCollapsedUnknowns()}},
+		{name: "sentence splitter", text: "a\nb. c", options: []EncodeOption{WithEncodeSentenceSplitter(NewlineSplitter())}},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			expectedTokens, err := model.Encode(strings.NewReader(tc.text), tc.options...)
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+
+			expectedIDs, err := model.EncodeIDs(strings.NewReader(tc.text), tc.options...)
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+
+			var (
+				tokens []string
+				ids    []int
+			)
+
+			stream := model.EncodeStream(context.Background(), strings.NewReader(tc.text), tc.options...)
+			for stream.Next() {
+				tokens = append(tokens, stream.Token())
+				ids = append(ids, stream.ID())
+			}
+
+			if err := stream.Err(); err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+
+			if len(expectedTokens) == 0 && len(tokens) == 0 {
+				return
+			}
+
+			if !reflect.DeepEqual(expectedTokens, tokens) {
+				t.Errorf("Expected: %v\nGot: %v\n", expectedTokens, tokens)
+			}
+
+			if !reflect.DeepEqual(expectedIDs, ids) {
+				t.Errorf("Expected IDs: %v\nGot: %v\n", expectedIDs, ids)
+			}
+		})
+	}
+}
+
+func TestBPE_EncodeStream_Endless(t *testing.T) {
+	model := newTruncationModel()
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	stream := model.EncodeStream(ctx, &endlessReader{data: []byte("a b c\nd e\n")})
+
+	var count int
+	for stream.Next() {
+		count++
+		if count == 1000 {
+			cancel()
+		}
+	}
+
+	if stream.Err() != context.Canceled {
+		t.Errorf("Expected cancellation. Got: %v", stream.Err())
+	}
+
+	if cap(stream.tokens.tokens) > 16 {
+		t.Errorf("Expected tokens of a single sentence to be kept. Got capacity: %d", cap(stream.tokens.tokens))
+	}
+}
+
+func TestBPE_EncodeStream_WithEncodeScanBufferSize(t *testing.T) {
+	model := newTruncationModel()
+	model.splitter = NoSplitter()
+
+	text := strings.Repeat("a b ", 50000)
+	option := WithEncodeScanBufferSize(1024 * 1024)
+
+	expected, err := model.Encode(strings.NewReader(text), option)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	var count int
+
+	stream := model.EncodeStream(context.Background(), strings.NewReader(text), option)
+	for stream.Next() {
+		if stream.Token() != expected[count] {
+			t.Fatalf("Token %d. Expected: %q, got: %q", count, expected[count], stream.Token())
+		}
+
+		count++
+	}
+
+	if err := stream.Err(); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	if count != len(expected) {
+		t.Errorf("Expected %d tokens. Got: %d", len(expected), count)
+	}
+}
+
+func TestBPE_EncodeStream_Errors(t *testing.T) {
+	model := newTruncationModel()
+	model.splitter = NoSplitter()
+
+	tt := []struct {
+		name    string
+		text    string
+		options []EncodeOption
+	}{
+		{name: "truncation", text: "a", options: []EncodeOption{WithMaxLength(2)}},
+		{name: "sentence longer than the default buffer", text: strings.Repeat("a ", 70000)},
+		{name: "sentence longer than the buffer", text: "a b c", options: []EncodeOption{WithEncodeScanBufferSize(3)}},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			stream := model.EncodeStream(context.Background(), strings.NewReader(tc.text), tc.options...)
+			if stream.Next() {
+				t.Errorf("Unexpected token: %q", stream.Token())
+			}
+
+			if stream.Err() == nil {
+				t.Error("Error expected")
+			}
+		})
+	}
+}
@@ -5,8 +5,9 @@ import (
 	"context"
 	"io"
 	"strings"
+	"sync"
+	"time"
 	"unicode"
-	"unicode/utf8"
 
 	"github.com/pkg/errors"
 )
@@ -15,6 +16,7 @@ const (
 	defaultMaxNumberOfTokens = 50000
 	defaultMaxTokenLength    = 32
 	maxScanBufferSize        = 64 * 1024
+	sentencesBatchSize       = 256
 
 	BeginOfWord     = "<w>"
 	EndOfWord       = "</w>"
@@ -27,15 +29,43 @@ const (
 func Train(ctx context.Context, source io.Reader, opts ...TrainOption) (*BPE, error) {
 	options := defaultTrainOptions()
 	options.Apply(opts...)
+	options.startProgress()
+	options.startCheckpoints(nil, 0)
 
 	tft, err := calculateTokensFrequency(ctx, source, options)
 	if err != nil {
 		return nil, err
 	}
 
-	model := newModelFromTokensFrequencyTable(tft, options.MaxNumberOfTokens)
+	return newModelFromFrequencyTable(tft, options), nil
+}
+
+// newModelFromFrequencyTable selects vocabulary from statistics the way options define.
+func newModelFromFrequencyTable(tft tokensFrequencyTable, options *trainOptions) *BPE {
+	options.progress.setCandidates(len(tft))
+	options.progress.setPhase(PhaseSelecting)
+	defer options.progress.finish()
+
+	var model *BPE
+
+	if options.SubstringFrequency && !options.ByteLevel {
+		model = newModelWithSpecialTokens(substringVocab(tft, options, nil), options.SpecialTokens)
+	} else {
+		model = newModelFromWordsFrequencyTable(tft, options)
+	}
+
+	model.normalizers = options.Normalizers
+	model.preTokenizers = options.PreTokenizers
+	model.splitter = options.SentenceSplitter
+
+	if options.KeepStatistics {
+		model.statistics = &trainingStatistics{
+			Options:     newSavedTrainOptions(options),
+			Frequencies: tft,
+		}
+	}
 
-	return model, nil
+	return model
 }
 
 func defaultTrainOptions() *trainOptions {
@@ -43,14 +73,36 @@ func defaultTrainOptions() *trainOptions {
 		MaxNumberOfTokens: defaultMaxNumberOfTokens,
 		MaxTokenLength:    defaultMaxTokenLength,
 		ScanBufferSize:    maxScanBufferSize,
+		SpecialTokens:     DefaultSpecialTokens(),
+		Workers:           1,
+		ProgressInterval:  defaultProgressInterval,
 	}
 }
 
 type trainOptions struct {
-	MaxNumberOfTokens int
-	MaxTokenLength    int
-	ScanBufferSize    int
-	WordsOnly         bool
+	MaxNumberOfTokens  int
+	MaxTokenLength     int
+	ScanBufferSize     int
+	WordsOnly          bool
+	SubstringFrequency bool
+	ByteLevel          bool
+	SpecialTokens      SpecialTokens
+	Workers            int
+	Progress           func(TrainProgress)
+	ProgressInterval   time.Duration
+	CheckpointWrite    func(cp *checkpoint) error
+	CheckpointInterval time.Duration
+	KeepStatistics     bool
+	MaxCandidates      int
+	MinFrequency       int
+	LimitAlphabet      int
+	InitialAlphabet    string
+	Normalizers        []Normalizer
+	PreTokenizers      []PreTokenizer
+	SentenceSplitter   SentenceSplitter
+
+	progress   *progressTracker // Is set by training if Progress is set.
+	checkpoint *checkpointer    // Is set by training if CheckpointWrite is set.
 }
 
 func (o *trainOptions) Apply(opts ...TrainOption) {
@@ -59,6 +111,12 @@ func (o *trainOptions) Apply(opts ...TrainOption) {
 	}
 }
 
+// startProgress starts tracking of training progress if it's requested.
+func (o *trainOptions) startProgress() {
+	o.progress = newProgressTracker(o.Progress, o.ProgressInterval)
+	o.progress.setPhase(PhaseCounting)
+}
+
 type TrainOption func(opts *trainOptions)
 
 func WithMaxNumberOfTokens(n int) TrainOption {
@@ -85,22 +143,113 @@ func WithWordsOnly() TrainOption {
 	}
 }
 
+// WithMinFrequency excludes rare candidates from vocabulary. Substrings occurring less than n times
+// are never selected, pairs occurring less than n times are never merged. Alphabet is kept in full.
+func WithMinFrequency(n int) TrainOption {
+	return func(opts *trainOptions) {
+		opts.MinFrequency = n
+	}
+}
+
+// WithSubstringFrequency switches training to the legacy mode.
+// Vocabulary consists of the most frequent substrings of words instead of learned merges.
+// Alphabet goes first within WithMaxNumberOfTokens, so rare characters are encoded as unknown only if it doesn't fit.
+func WithSubstringFrequency() TrainOption {
+	return func(opts *trainOptions) {
+		opts.SubstringFrequency = true
+	}
+}
+
+// WithByteLevel makes model work with bytes instead of characters.
+// All 256 bytes are always part of the vocabulary so encoding never produces unknown tokens,
+// and spaces are encoded as well so decoding restores the original text exactly.
+// Byte-level models are always trained with merges.
+func WithByteLevel() TrainOption {
+	return func(opts *trainOptions) {
+		opts.ByteLevel = true
+	}
+}
+
+// WithSpecialTokens sets special tokens of the model including reserved ones.
+// Empty fields are replaced with the default values.
+func WithSpecialTokens(special SpecialTokens) TrainOption {
+	return func(opts *trainOptions) {
+		opts.SpecialTokens = special.withDefaults()
+	}
+}
+
+// WithReservedTokens adds extra special tokens like <pad> or <mask>.
+// They get fixed IDs and are never split by encoder.
+func WithReservedTokens(tokens ...string) TrainOption {
+	return func(opts *trainOptions) {
+		opts.SpecialTokens.Reserved = append(opts.SpecialTokens.Reserved, tokens...)
+	}
+}
+
+// WithWorkers sets number of goroutines counting statistics of the source.
+// The result doesn't depend on the number of workers unless WithMaxCandidates is set: every worker prunes
+// its own share of candidates, so rare tokens kept or dropped could differ.
+func WithWorkers(n int) TrainOption {
+	return func(opts *trainOptions) {
+		if n < 1 {
+			n = 1
+		}
+
+		opts.Workers = n
+	}
+}
+
+// WithProgress sets callback which receives training progress periodically, on every phase change
+// and once training is done. Callback is never called concurrently and should return quickly.
+func WithProgress(report func(TrainProgress)) TrainOption {
+	return func(opts *trainOptions) {
+		opts.Progress = report
+	}
+}
+
+// WithProgressInterval sets minimal interval between progress reports. Default is one second.
+func WithProgressInterval(interval time.Duration) TrainOption {
+	return func(opts *trainOptions) {
+		opts.ProgressInterval = interval
+	}
+}
+
 type tokensFrequencyTable map[string]int
 
 func calculateTokensFrequency(ctx context.Context, r io.Reader, options *trainOptions) (tokensFrequencyTable, error) {
-	tokensFrequency := make(tokensFrequencyTable, options.MaxNumberOfTokens) // Approximate size. Avoid extra allocations.
-	scanner := bufio.NewScanner(r)
-	scanner.Split(scanSentences)
+	split := sentenceSplitFunc(options.SentenceSplitter, options.ByteLevel)
+
+	var consumed int64 // Bytes of the source consumed by the scanner.
+
+	scanner := bufio.NewScanner(options.progress.reader(r))
+	scanner.Split(countConsumed(split, &consumed))
 	scanner.Buffer(make([]byte, 0, options.ScanBufferSize), options.ScanBufferSize)
 
-	// TODO read in separate threads.
+	if options.Workers > 1 {
+		return calculateTokensFrequencyConcurrently(ctx, scanner, &consumed, options)
+	}
+
+	tokensFrequency := make(tokensFrequencyTable, options.MaxNumberOfTokens) // Approximate size. Avoid extra allocations.
+
+	var counted int64 // Bytes of the source counted so far.
+
 	for scanner.Scan() {
 		select {
 		case <-ctx.Done():
-			return nil, ctx.Err()
+			return nil, options.checkpoint.cancel(ctx, counted, tokensFrequency)
 		default:
-			sentence := scanner.Text()
-			tokenize(tokensFrequency, sentence, options.MaxTokenLength, options.WordsOnly)
+		}
+
+		candidates := len(tokensFrequency)
+		countSentence(tokensFrequency, scanner.Text(), options)
+		pruneCandidates(tokensFrequency, options.MaxCandidates)
+		options.progress.counted(1, len(tokensFrequency)-candidates)
+		counted = consumed
+
+		if options.checkpoint.due() {
+			if err := options.checkpoint.save(counted, tokensFrequency); err != nil {
+				return nil, err
+			}
 		}
 	}
 
@@ -111,94 +260,204 @@ func calculateTokensFrequency(ctx context.Context, r io.Reader, options *trainOp
 	return tokensFrequency, nil
 }
 
-const abbreviationLength = 4
+// calculateTokensFrequencyConcurrently fans sentences out to workers.
+// Every worker counts into its own table and tables are merged at the end,
+// so the result is the same as with the single worker.
+func calculateTokensFrequencyConcurrently(
+	ctx context.Context,
+	scanner *bufio.Scanner,
+	consumed *int64,
+	options *trainOptions,
+) (tokensFrequencyTable, error) {
+	batches := make(chan []string, options.Workers)
+	shards := make([]tokensFrequencyTable, options.Workers)
+	workers := sync.WaitGroup{}
+	pending := sync.WaitGroup{} // Batches sent to workers but not counted yet.
+	candidatesLimit := workerCandidatesLimit(options)
+
+	for i := range shards {
+		shards[i] = make(tokensFrequencyTable, options.MaxNumberOfTokens/options.Workers)
+		workers.Add(1)
+
+		go func(tft tokensFrequencyTable) {
+			defer workers.Done()
+
+			for batch := range batches {
+				candidates := len(tft)
+
+				for _, sentence := range batch {
+					countSentence(tft, sentence, options)
+					pruneCandidates(tft, candidatesLimit)
+				}
 
-// isEndOfSentence checks some heuristics to understand whether it's the end of sentence or not.
-func isEndOfSentence(lastSymbol rune, prev, next []byte) bool {
-	if len(prev) == 0 {
-		return false
+				options.progress.counted(len(batch), len(tft)-candidates)
+				pending.Done()
+			}
+		}(shards[i])
 	}
 
-	switch lastSymbol {
-	case '\r', '\n', '!', '?':
-		return true
-	case '.':
-		// Sad but dot isn't explicit marker of the end of sentence.
-		// It can be used for name or other abbreviation.
+	var sent int64 // Bytes of the source sent to workers.
 
-		// Check last runes.
-		// If they're \w{abbreviationLength} – looks like a Mrs., Dr. or other abbreviation.
-		// It previous and next rune are numbers – it's a float.
-		// Otherwise it looks like an end of sentence.
-		prevRune, width := utf8.DecodeLastRune(prev)
-		if unicode.IsLetter(prevRune) {
-			nextAfterCurrent := prevRune // Is needed to check letter capitalization after getting first space.
-			margin := width
+	err := scanBatches(ctx, scanner, func(batch []string) error {
+		pending.Add(1)
 
-			// Let's find the space.
-			for i := 0; i < abbreviationLength; i++ {
-				if len(prev)-margin <= 0 {
-					return false
-				}
+		select {
+		case <-ctx.Done():
+			pending.Done()
+			return ctx.Err()
+		case batches <- batch:
+			sent = *consumed
+		}
 
-				currentRune, currentWidth := utf8.DecodeLastRune(prev[:len(prev)-margin])
+		if !options.checkpoint.due() {
+			return nil
+		}
 
-				// We've found the space. Let's check that the next character after space is a capitalized letter.
-				if unicode.IsSpace(currentRune) {
-					return !unicode.IsUpper(nextAfterCurrent)
-				}
+		// Workers must be idle to make a consistent snapshot.
+		pending.Wait()
 
-				// Is token is inside some group?
-				if strings.ContainsAny(string(currentRune), `[({"'`) {
-					return false
-				}
+		return options.checkpoint.save(sent, shards...)
+	})
 
-				// If it's not letter it's not an abbreviation.
-				if !unicode.IsLetter(currentRune) {
-					return true
-				}
+	close(batches)
+	workers.Wait()
 
-				nextAfterCurrent = currentRune
-				margin += currentWidth
-			}
+	if err != nil && err == ctx.Err() {
+		err = options.checkpoint.cancel(ctx, sent, shards...)
+	}
+
+	if err != nil {
+		return nil, err
+	}
+
+	tft := mergeTokensFrequencyTables(shards)
+	pruneCandidates(tft, options.MaxCandidates)
 
-			// If the last n characters was letters it's probably is the end of string.
-			return true
+	return tft, nil
+}
+
+// scanBatches passes scanned sentences to send by batches.
+func scanBatches(ctx context.Context, scanner *bufio.Scanner, send func(batch []string) error) error {
+	batch := make([]string, 0, sentencesBatchSize)
+
+	for scanner.Scan() {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		default:
 		}
 
-		if unicode.IsDigit(prevRune) {
-			if len(next) == 0 {
-				return true
-			}
+		batch = append(batch, scanner.Text())
+		if len(batch) < sentencesBatchSize {
+			continue
+		}
 
-			nextRune, _ := utf8.DecodeRune(next)
-			// Looks like it's a float number.
-			if unicode.IsDigit(nextRune) {
-				return false
-			}
+		if err := send(batch); err != nil {
+			return err
 		}
 
-		return true
+		batch = make([]string, 0, sentencesBatchSize)
 	}
 
-	return false
+	if err := scanner.Err(); err != nil && err != io.EOF {
+		return errors.Wrap(err, "file scan")
+	}
+
+	if len(batch) > 0 {
+		return send(batch)
+	}
+
+	return nil
+}
+
+// mergeTokensFrequencyTables sums up all tables into the biggest one.
+func mergeTokensFrequencyTables(tables []tokensFrequencyTable) tokensFrequencyTable {
+	biggest := 0
+	for i, tft := range tables {
+		if len(tft) > len(tables[biggest]) {
+			biggest = i
+		}
+	}
+
+	result := tables[biggest]
+
+	for i, tft := range tables {
+		if i == biggest {
+			continue
+		}
+
+		for token, count := range tft {
+			result[token] += count
+		}
+	}
+
+	return result
+}
+
+// countSentence adds statistics of the sentence to the table. Reserved tokens are cut out of the raw text.
+func countSentence(tft tokensFrequencyTable, sentence string, options *trainOptions) {
+	reserved := options.SpecialTokens.addedTokens()
+	if len(reserved) == 0 {
+		countText(tft, sentence, options)
+		return
+	}
+
+	for _, segment := range splitAtomicTokens(sentence, reserved) {
+		if !segment.added {
+			countText(tft, segment.text, options)
+		}
+	}
+}
+
+// countText adds statistics of the text without reserved tokens to the table.
+func countText(tft tokensFrequencyTable, text string, options *trainOptions) {
+	text = normalize(text, options.Normalizers)
+	words := preTokenize(text, options.PreTokenizers, options.SpecialTokens, options.ByteLevel)
+
+	switch {
+	case options.ByteLevel:
+		countWordsWithSpaces(tft, words, options.SpecialTokens)
+	case options.SubstringFrequency:
+		tokenize(tft, words, options.MaxTokenLength, options.WordsOnly, options.SpecialTokens)
+	default:
+		countWords(tft, words, options.WordsOnly, options.SpecialTokens)
+	}
 }
 
 // Preserve Unicode symbols.
-func tokenize(tft tokensFrequencyTable, sentence string, maxTokenLength int, wordsOnly bool) {
-	words := strings.Fields(sentence)
+func tokenize(tft tokensFrequencyTable, words []string, maxTokenLength int, wordsOnly bool, special SpecialTokens) {
+	for _, word := range words {
+		if (wordsOnly && !isWord(word)) || special.isReserved(word) {
+			continue
+		}
 
+		tokenizeWord(tft, wordSymbols(word, special), maxTokenLength)
+	}
+}
+
+// countWords counts words of the sentence. Merges are learned from these counts.
+func countWords(wft tokensFrequencyTable, words []string, wordsOnly bool, special SpecialTokens) {
 	for _, word := range words {
-		if wordsOnly && !isWord(word) {
+		if (wordsOnly && !isWord(word)) || special.isReserved(word) {
 			continue
 		}
 
-		wordTokens := strings.Split(word, "")
+		wft[word]++
+	}
+}
 
-		// Add special tokens.
-		wordTokens[0] = BeginOfWord + wordTokens[0]
-		wordTokens[len(wordTokens)-1] = wordTokens[len(wordTokens)-1] + EndOfWord
-		tokenizeWord(tft, wordTokens, maxTokenLength)
+// countWordsWithSpaces counts words of the sentence together with their leading spaces.
+// Reserved tokens are skipped but their leading spaces are counted.
+func countWordsWithSpaces(wft tokensFrequencyTable, words []string, special SpecialTokens) {
+	for _, word := range words {
+		token := strings.TrimLeftFunc(word, unicode.IsSpace)
+		if special.isReserved(token) {
+			word = word[:len(word)-len(token)]
+		}
+
+		if word != "" {
+			wft[word]++
+		}
 	}
 }
 
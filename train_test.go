@@ -3,6 +3,8 @@ package bpe
 import (
 	"bytes"
 	"context"
+	"fmt"
+	"io/ioutil"
 	"reflect"
 	"strings"
 	"testing"
@@ -10,6 +12,129 @@ import (
 )
 
 func TestTrain(t *testing.T) {
+	tt := []struct {
+		name      string
+		input     string
+		options   []TrainOption
+		expected  map[string]struct{}
+		withError bool
+	}{
+		{
+			name:  "word",
+			input: "apple",
+			expected: map[string]struct{}{
+				BeginOfWord + "a":                 {},
+				"p":                               {},
+				"l":                               {},
+				"e" + EndOfWord:                   {},
+				BeginOfWord + "ap":                {},
+				BeginOfWord + "app":               {},
+				BeginOfWord + "appl":              {},
+				BeginOfWord + "apple" + EndOfWord: {},
+			},
+		},
+		{
+			name:  "frequent pair wins",
+			input: "low lower lowest",
+			options: []TrainOption{
+				WithMaxNumberOfTokens(14),
+			},
+			expected: map[string]struct{}{
+				BeginOfWord + "l":                 {},
+				"o":                               {},
+				"w":                               {},
+				"e":                               {},
+				"w" + EndOfWord:                   {},
+				"r" + EndOfWord:                   {},
+				"s":                               {},
+				"t" + EndOfWord:                   {},
+				BeginOfWord + "lo":                {},
+				BeginOfWord + "low":               {},
+				BeginOfWord + "lowe":              {},
+				BeginOfWord + "low" + EndOfWord:   {},
+				BeginOfWord + "lower" + EndOfWord: {},
+				BeginOfWord + "lowes":             {},
+			},
+		},
+		{
+			name:  "max token length",
+			input: "aaaaaaaaa",
+			options: []TrainOption{
+				WithMaxTokenLength(3),
+			},
+			expected: map[string]struct{}{
+				BeginOfWord + "a":   {},
+				"a":                 {},
+				"a" + EndOfWord:     {},
+				"aa":                {},
+				BeginOfWord + "aaa": {},
+				"aa" + EndOfWord:    {},
+			},
+		},
+		{
+			name:  "max tokens",
+			input: "aaaaaaaaa",
+			options: []TrainOption{
+				WithMaxNumberOfTokens(1),
+			},
+			expected: map[string]struct{}{
+				BeginOfWord + "a": {},
+				"a":               {},
+				"a" + EndOfWord:   {},
+			},
+		},
+		{
+			name:  "words only",
+			input: "foo foo2",
+			options: []TrainOption{
+				WithWordsOnly(),
+			},
+			expected: map[string]struct{}{
+				BeginOfWord + "f":               {},
+				"o":                             {},
+				"o" + EndOfWord:                 {},
+				BeginOfWord + "fo":              {},
+				BeginOfWord + "foo" + EndOfWord: {},
+			},
+		},
+		{
+			name:     "empty",
+			input:    "",
+			expected: map[string]struct{}{},
+		},
+		{
+			name:  "error",
+			input: "asdasdasd",
+			options: []TrainOption{
+				WithScanBufferSize(1),
+			},
+			withError: true, // bufio.Scanner: token too long
+		},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			m, err := Train(context.Background(), strings.NewReader(tc.input), tc.options...)
+			if err != nil && !tc.withError {
+				t.Errorf("Unexpected error: %v", err)
+			}
+
+			if m == nil {
+				if !tc.withError {
+					t.Error("Model should not be nil")
+				}
+
+				return
+			}
+
+			if !reflect.DeepEqual(tc.expected, m.vocab) {
+				t.Errorf("Expected: %v\nGot: %v\n", tc.expected, m.vocab)
+			}
+		})
+	}
+}
+
+func TestTrain_SubstringFrequency(t *testing.T) {
 	tt := []struct {
 		name      string
 		input     string
@@ -128,6 +253,26 @@ func TestTrain(t *testing.T) {
 				"oo" + EndOfWord:                {},
 			},
 		},
+		{
+			name:   "ties are broken by length",
+			input:  "ab",
+			option: WithMaxNumberOfTokens(3), // Alphabet goes first.
+			expected: map[string]struct{}{
+				BeginOfWord + "a":              {},
+				"b" + EndOfWord:                {},
+				BeginOfWord + "ab" + EndOfWord: {},
+			},
+		},
+		{
+			name:   "min frequency",
+			input:  "foo fob",
+			option: WithMinFrequency(2),
+			expected: map[string]struct{}{
+				BeginOfWord + "f":  {},
+				BeginOfWord + "fo": {},
+				"o":                {},
+			},
+		},
 		{
 			name:     "empty",
 			input:    "",
@@ -143,7 +288,7 @@ func TestTrain(t *testing.T) {
 
 	for _, tc := range tt {
 		t.Run(tc.name, func(t *testing.T) {
-			options := make([]TrainOption, 0)
+			options := []TrainOption{WithSubstringFrequency()}
 			if tc.option != nil {
 				options = append(options, tc.option)
 			}
@@ -168,21 +313,150 @@ func TestTrain(t *testing.T) {
 	}
 }
 
+func TestTrain_Reproducible(t *testing.T) {
+	corpus, err := ioutil.ReadFile("example.txt")
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	for _, options := range [][]TrainOption{
+		{WithMaxNumberOfTokens(100)},
+		{WithMaxNumberOfTokens(100), WithSubstringFrequency()},
+	} {
+		expected, err := Train(context.Background(), bytes.NewReader(corpus), options...)
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+
+		for i := 0; i < 5; i++ {
+			actual, err := Train(context.Background(), bytes.NewReader(corpus), options...)
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+
+			if !reflect.DeepEqual(expected.tokens, actual.tokens) {
+				t.Fatalf("Expected: %v\nGot: %v\n", expected.tokens, actual.tokens)
+			}
+		}
+	}
+}
+
 func TestTrain_WithTimeout(t *testing.T) {
-	source := bytes.NewBuffer(nil)
-	ctx, cancel := context.WithTimeout(context.Background(), time.Millisecond)
-	defer cancel()
+	for _, workers := range []int{1, 4} {
+		source := &endlessReader{data: []byte("some very important data. ")}
+		ctx, cancel := context.WithTimeout(context.Background(), time.Millisecond)
 
-	go func() {
-		for {
-			source.Write([]byte("some very important data."))
+		_, err := Train(ctx, source, WithWorkers(workers))
+		if err != context.DeadlineExceeded {
+			t.Errorf("Context deadline error is expected with %d workers. Got: %v", workers, err)
 		}
-	}()
 
-	_, err := Train(ctx, source)
-	if err != context.DeadlineExceeded {
-		t.Errorf("Context deadline error is expected")
+		cancel()
+	}
+}
+
+func TestTrain_WithWorkers(t *testing.T) {
+	corpus, err := ioutil.ReadFile("example.txt")
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	tt := []struct {
+		name    string
+		options []TrainOption
+	}{
+		{
+			name:    "merges",
+			options: []TrainOption{WithMaxNumberOfTokens(500)},
+		},
+		{
+			name:    "byte level",
+			options: []TrainOption{WithMaxNumberOfTokens(500), WithByteLevel()},
+		},
+		{
+			name:    "substring frequency",
+			options: []TrainOption{WithSubstringFrequency()},
+		},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			expected, err := Train(context.Background(), bytes.NewReader(corpus), tc.options...)
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+
+			for _, workers := range []int{2, 3, 8} {
+				actual, err := Train(context.Background(), bytes.NewReader(corpus), append(tc.options, WithWorkers(workers))...)
+				if err != nil {
+					t.Fatalf("Unexpected error: %v", err)
+				}
+
+				if !reflect.DeepEqual(expected.vocab, actual.vocab) {
+					t.Errorf("Vocabulary learned by %d workers differs.\nExpected: %v\nGot: %v\n", workers, expected.vocab, actual.vocab)
+				}
+			}
+		})
+	}
+}
+
+func TestCalculateTokensFrequency_WithWorkers(t *testing.T) {
+	corpus := strings.Repeat("Lorem ipsum dolor sit amet. Consectetur adipiscing elit!\n", 1000)
+	options := defaultTrainOptions()
+	options.SubstringFrequency = true
+
+	expected, err := calculateTokensFrequency(context.Background(), strings.NewReader(corpus), options)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
 	}
+
+	options.Workers = 4
+
+	actual, err := calculateTokensFrequency(context.Background(), strings.NewReader(corpus), options)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	if !reflect.DeepEqual(expected, actual) {
+		t.Errorf("Expected: %v\nGot: %v\n", expected, actual)
+	}
+}
+
+func BenchmarkTrain(b *testing.B) {
+	corpus, err := ioutil.ReadFile("example.txt")
+	if err != nil {
+		b.Fatalf("Unexpected error: %v", err)
+	}
+
+	corpus = bytes.Repeat(corpus, 200)
+
+	for _, workers := range []int{1, 2, 4, 8} {
+		b.Run(fmt.Sprintf("workers=%d", workers), func(b *testing.B) {
+			b.SetBytes(int64(len(corpus)))
+
+			for i := 0; i < b.N; i++ {
+				_, err := Train(context.Background(), bytes.NewReader(corpus), WithSubstringFrequency(), WithWorkers(workers))
+				if err != nil {
+					b.Fatalf("Unexpected error: %v", err)
+				}
+			}
+		})
+	}
+}
+
+// endlessReader repeats data forever.
+type endlessReader struct {
+	data []byte
+	pos  int
+}
+
+func (r *endlessReader) Read(p []byte) (int, error) {
+	for i := range p {
+		p[i] = r.data[r.pos]
+		r.pos = (r.pos + 1) % len(r.data)
+	}
+
+	return len(p), nil
 }
 
 func TestTokenize(t *testing.T) {
@@ -296,7 +570,7 @@ func TestTokenize(t *testing.T) {
 	for _, tc := range tt {
 		t.Run(tc.word, func(t *testing.T) {
 			actualTokens := make(tokensFrequencyTable)
-			tokenize(actualTokens, tc.word, tc.maxTokenSize, tc.wordsOnly)
+			tokenize(actualTokens, strings.Fields(tc.word), tc.maxTokenSize, tc.wordsOnly, DefaultSpecialTokens())
 
 			if !reflect.DeepEqual(tc.expected, actualTokens) {
 				t.Errorf("Expected: %v\nGot: %v\n", tc.expected, actualTokens)
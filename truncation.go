@@ -0,0 +1,365 @@
+package bpe
+
+import (
+	"io"
+
+	"github.com/pkg/errors"
+)
+
+// minSequenceLength is begin and end of sentence tokens and at least one token of the text.
+const minSequenceLength = 3
+
+// PadToken is the default padding token. Model must know it as one of its special tokens,
+// e.g. it could be passed as a reserved one.
+const PadToken = "<pad>"
+
+// TruncationStrategy defines which tokens are dropped when encoding is longer than the maximum length.
+type TruncationStrategy int
+
+const (
+	// TruncateTail keeps the beginning of the text and drops its end.
+	TruncateTail TruncationStrategy = iota
+
+	// TruncateHead keeps the end of the text and drops its beginning.
+	TruncateHead
+
+	// TruncateLongestFirst drops tokens from the end of the longest text of a pair one by one.
+	// Single text is truncated like with TruncateTail.
+	TruncateLongestFirst
+)
+
+// PaddingSide defines where padding tokens are added.
+type PaddingSide int
+
+const (
+	PadRight PaddingSide = iota
+	PadLeft
+)
+
+// WithMaxLength limits number of tokens including begin and end of sentence tokens.
+// It must be at least 3 per sequence, so EncodePair requires at least 6.
+// Tokens which don't fit are split into windows of the same length which go to Encoding.Overflowing.
+// Window which starts or ends inside of a sentence gets begin or end of sentence token, so they are never lost.
+// Encode and EncodeIDs return the first window only.
+func WithMaxLength(n int) EncodeOption {
+	return func(opts *encodeOptions) {
+		opts.MaxLength = n
+	}
+}
+
+// WithTruncation sets which tokens are dropped when encoding is longer than the maximum length.
+// TruncateTail is used by default.
+func WithTruncation(strategy TruncationStrategy) EncodeOption {
+	return func(opts *encodeOptions) {
+		opts.Truncation = strategy
+	}
+}
+
+// WithStride makes neighbour windows of truncated encoding share n tokens.
+// It must be less than the maximum length minus 2 which are kept for begin and end of sentence tokens.
+func WithStride(n int) EncodeOption {
+	return func(opts *encodeOptions) {
+		opts.Stride = n
+	}
+}
+
+// WithPadding makes encoding at least of the maximum length by adding pad tokens to the given side.
+func WithPadding(side PaddingSide) EncodeOption {
+	return func(opts *encodeOptions) {
+		opts.Padding = true
+		opts.PaddingSide = side
+	}
+}
+
+// WithPadToken sets padding token. It must be one of special tokens of the model. PadToken is used by default.
+func WithPadToken(token string) EncodeOption {
+	return func(opts *encodeOptions) {
+		opts.PadToken = token
+	}
+}
+
+// WithPadToMultipleOf makes padded encoding length a multiple of n. It requires WithPadding.
+func WithPadToMultipleOf(n int) EncodeOption {
+	return func(opts *encodeOptions) {
+		opts.PadToMultipleOf = n
+	}
+}
+
+// EncodePair encodes pair of texts into a single encoding, e.g. a question and a context.
+// Tokens of the second text have Sequence 1 and continue word and sentence indexes of the first one.
+// Their spans are offsets in the second text.
+func (b *BPE) EncodePair(first, second io.Reader, opts ...EncodeOption) (*Encoding, error) {
+	options := defaultEncodeOptions()
+	options.Apply(opts...)
+
+	if err := options.validateLength(2); err != nil {
+		return nil, err
+	}
+
+	firstEncoding, err := b.encodeDetailed(first, options)
+	if err != nil {
+		return nil, errors.Wrap(err, "first text")
+	}
+
+	secondEncoding, err := b.encodeDetailed(second, options)
+	if err != nil {
+		return nil, errors.Wrap(err, "second text")
+	}
+
+	words, sentences := 0, 0
+	for _, t := range firstEncoding.Tokens {
+		if t.Word >= words {
+			words = t.Word + 1
+		}
+
+		sentences = t.Sentence + 1
+	}
+
+	for i := range secondEncoding.Tokens {
+		t := &secondEncoding.Tokens[i]
+		t.Sequence = 1
+		t.Sentence += sentences
+
+		if t.Word != noWord {
+			t.Word += words
+		}
+	}
+
+	if options.MaxLength > 0 && options.Truncation == TruncateLongestFirst {
+		b.truncateLongestFirst(firstEncoding, secondEncoding, options.MaxLength)
+	}
+
+	pair := &Encoding{Tokens: append(firstEncoding.Tokens, secondEncoding.Tokens...)}
+
+	return b.fit(pair, options)
+}
+
+// fixedLength checks whether encoding must be truncated or padded.
+func (o *encodeOptions) fixedLength() bool {
+	return o.MaxLength > 0 || o.Padding
+}
+
+// validateLength checks that options leave room for at least one token of every sequence
+// besides its begin and end of sentence tokens.
+func (o *encodeOptions) validateLength(sequences int) error {
+	if o.MaxLength < 0 || o.Stride < 0 || o.PadToMultipleOf < 0 {
+		return errors.New("negative length")
+	}
+
+	if o.MaxLength > 0 && o.MaxLength < minSequenceLength*sequences {
+		return errors.Errorf("max length %d must be at least %d per sequence", o.MaxLength, minSequenceLength)
+	}
+
+	// Window keeps room for begin and end of sentence tokens, so a bigger stride leaves no room for new tokens.
+	if o.Stride > 0 && o.MaxLength > 0 && o.Stride >= o.MaxLength-2 {
+		return errors.Errorf("stride %d must be less than max length %d minus 2", o.Stride, o.MaxLength)
+	}
+
+	return nil
+}
+
+// fit truncates and pads encoding according to options.
+func (b *BPE) fit(e *Encoding, options *encodeOptions) (*Encoding, error) {
+	if err := options.validateLength(1); err != nil {
+		return nil, err
+	}
+
+	if options.MaxLength > 0 && e.Len() > options.MaxLength {
+		windows := b.windows(e.Tokens, options.MaxLength, options.Stride, options.Truncation == TruncateHead)
+		e = &Encoding{Tokens: windows[0]}
+
+		for _, window := range windows[1:] {
+			e.Overflowing = append(e.Overflowing, &Encoding{Tokens: window})
+		}
+	}
+
+	if !options.Padding {
+		return e, nil
+	}
+
+	pad, err := b.padToken(options)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, encoding := range append([]*Encoding{e}, e.Overflowing...) {
+		encoding.pad(pad, options)
+	}
+
+	return e, nil
+}
+
+// padToken returns padding token which is requested by options.
+func (b *BPE) padToken(options *encodeOptions) (EncodedToken, error) {
+	token := options.PadToken
+	if token == "" {
+		token = PadToken
+	}
+
+	id, ok := b.TokenToID(token)
+	if !ok || !b.specialTokens().isSpecial(token) {
+		return EncodedToken{}, errors.Errorf("pad token %q is not a special token of the model", token)
+	}
+
+	return EncodedToken{
+		Token:    token,
+		ID:       id,
+		Special:  true,
+		Word:     noWord,
+		Sentence: -1,
+		Sequence: -1,
+		Padding:  true,
+	}, nil
+}
+
+// pad adds pad tokens up to the maximum length rounded up to the multiple requested by options.
+func (e *Encoding) pad(pad EncodedToken, options *encodeOptions) {
+	length := options.MaxLength
+	if length < e.Len() {
+		length = e.Len()
+	}
+
+	if n := options.PadToMultipleOf; n > 0 && length%n != 0 {
+		length += n - length%n
+	}
+
+	padding := make([]EncodedToken, length-e.Len())
+	for i := range padding {
+		padding[i] = pad
+	}
+
+	if options.PaddingSide == PadLeft {
+		e.Tokens = append(padding, e.Tokens...)
+	} else {
+		e.Tokens = append(e.Tokens, padding...)
+	}
+}
+
+// truncateLongestFirst drops the last tokens of the longest encoding one by one until both fit into n tokens.
+// Dropped tokens aren't kept as overflowing.
+func (b *BPE) truncateLongestFirst(first, second *Encoding, n int) {
+	firstLength, secondLength := first.Len(), second.Len()
+
+	for firstLength+secondLength > n && firstLength+secondLength > 0 {
+		if firstLength > secondLength {
+			firstLength--
+		} else {
+			secondLength--
+		}
+	}
+
+	first.Tokens = b.head(first.Tokens, firstLength)
+	second.Tokens = b.head(second.Tokens, secondLength)
+}
+
+// head returns the first window of up to n tokens.
+func (b *BPE) head(tokens []EncodedToken, n int) []EncodedToken {
+	switch {
+	case n == 0:
+		return nil
+	case n >= len(tokens):
+		return tokens
+	default:
+		return b.windows(tokens, n, 0, false)[0]
+	}
+}
+
+// windows splits tokens into windows of up to n tokens which must be at least minSequenceLength.
+// Neighbour windows share stride tokens.
+// Window which starts or ends inside of a sentence gets begin or end of sentence token.
+// If fromEnd is set windows are taken from the end, so the first window keeps the last tokens.
+func (b *BPE) windows(tokens []EncodedToken, n, stride int, fromEnd bool) [][]EncodedToken {
+	special := b.specialTokens()
+	opening, closing := special.BeginOfSentence, special.EndOfSentence
+
+	if fromEnd {
+		tokens = reversedTokens(tokens)
+		opening, closing = closing, opening
+	}
+
+	is := func(t EncodedToken, marker string) bool {
+		return t.Special && !t.Padding && t.Token == marker
+	}
+
+	var result [][]EncodedToken
+
+	for start := 0; start < len(tokens); {
+		window := make([]EncodedToken, 0, n)
+		if !is(tokens[start], opening) {
+			window = append(window, b.marker(opening, tokens[start]))
+		}
+
+		end := start
+		for ; end < len(tokens); end++ {
+			// Keep room for the closing marker unless the token is the one.
+			reserved := 1
+			if is(tokens[end], closing) {
+				reserved = 0
+			}
+
+			if len(window)+1+reserved > n {
+				break
+			}
+
+			window = append(window, tokens[end])
+		}
+
+		// Sentence which has only begun is left for the next window.
+		if end > start+1 && is(tokens[end-1], opening) {
+			window = window[:len(window)-1]
+			end--
+		}
+
+		if !is(window[len(window)-1], closing) {
+			window = append(window, b.marker(closing, window[len(window)-1]))
+		}
+
+		result = append(result, window)
+
+		if end == len(tokens) {
+			break
+		}
+
+		if start+1 > end-stride {
+			start++
+		} else {
+			start = end - stride
+		}
+
+		// Closing markers aren't repeated in the next window.
+		for start < end && is(tokens[start], closing) {
+			start++
+		}
+	}
+
+	if fromEnd {
+		for i := range result {
+			result[i] = reversedTokens(result[i])
+		}
+	}
+
+	return result
+}
+
+// marker returns begin or end of sentence token for the sentence which the token belongs to.
+func (b *BPE) marker(token string, of EncodedToken) EncodedToken {
+	id, _ := b.TokenToID(token)
+
+	return EncodedToken{
+		Token:    token,
+		ID:       id,
+		Special:  true,
+		Word:     noWord,
+		Sentence: of.Sentence,
+		Sequence: of.Sequence,
+	}
+}
+
+func reversedTokens(tokens []EncodedToken) []EncodedToken {
+	result := make([]EncodedToken, len(tokens))
+	for i, t := range tokens {
+		result[len(tokens)-1-i] = t
+	}
+
+	return result
+}
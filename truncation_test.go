@@ -0,0 +1,278 @@
+package bpe
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func newTruncationModel() *BPE {
+	var vocab []string
+	for _, word := range []string{"a", "b", "c", "d", "e"} {
+		vocab = append(vocab, BeginOfWord+word+EndOfWord)
+	}
+
+	special := DefaultSpecialTokens()
+	special.Reserved = []string{PadToken, "[PAD]"}
+
+	model := newModelWithSpecialTokens(vocab, special)
+	model.splitter = NewlineSplitter()
+
+	return model
+}
+
+func TestBPE_EncodeDetailed_Truncation(t *testing.T) {
+	const s, e = BeginOfSentence, EndOfSentence
+	w := func(word string) string { return BeginOfWord + word + EndOfWord }
+
+	tt := []struct {
+		name      string
+		options   []EncodeOption
+		expected  [][]string // The main window followed by overflowing ones.
+		withError bool
+	}{
+		{
+			name:     "fits",
+			options:  []EncodeOption{WithMaxLength(9)},
+			expected: [][]string{{s, w("a"), w("b"), w("c"), e, s, w("d"), w("e"), e}},
+		},
+		{
+			name:    "tail",
+			options: []EncodeOption{WithMaxLength(5)},
+			expected: [][]string{
+				{s, w("a"), w("b"), w("c"), e},
+				{s, w("d"), w("e"), e},
+			},
+		},
+		{
+			name:    "sentence markers are kept",
+			options: []EncodeOption{WithMaxLength(4)},
+			expected: [][]string{
+				{s, w("a"), w("b"), e},
+				{s, w("c"), e},
+				{s, w("d"), w("e"), e},
+			},
+		},
+		{
+			name:    "stride",
+			options: []EncodeOption{WithMaxLength(4), WithStride(1)},
+			expected: [][]string{
+				{s, w("a"), w("b"), e},
+				{s, w("b"), w("c"), e},
+				{s, w("d"), w("e"), e},
+			},
+		},
+		{
+			name:      "stride without room for new tokens",
+			options:   []EncodeOption{WithMaxLength(3), WithStride(1)},
+			withError: true,
+		},
+		{
+			name:      "stride without room for new tokens from head",
+			options:   []EncodeOption{WithMaxLength(3), WithStride(1), WithTruncation(TruncateHead)},
+			withError: true,
+		},
+		{
+			name:      "too short for markers",
+			options:   []EncodeOption{WithMaxLength(2)},
+			withError: true,
+		},
+		{
+			name:    "shortest",
+			options: []EncodeOption{WithMaxLength(3), WithTruncation(TruncateHead)},
+			expected: [][]string{
+				{s, w("e"), e},
+				{s, w("d"), e},
+				{s, w("c"), e},
+				{s, w("b"), e},
+				{s, w("a"), e},
+			},
+		},
+		{
+			name:    "head",
+			options: []EncodeOption{WithMaxLength(4), WithTruncation(TruncateHead)},
+			expected: [][]string{
+				{s, w("d"), w("e"), e},
+				{s, w("b"), w("c"), e},
+				{s, w("a"), e},
+			},
+		},
+		{
+			name:    "longest first truncates single text from tail",
+			options: []EncodeOption{WithMaxLength(5), WithTruncation(TruncateLongestFirst)},
+			expected: [][]string{
+				{s, w("a"), w("b"), w("c"), e},
+				{s, w("d"), w("e"), e},
+			},
+		},
+		{
+			name:     "padding",
+			options:  []EncodeOption{WithMaxLength(12), WithPadding(PadRight)},
+			expected: [][]string{{s, w("a"), w("b"), w("c"), e, s, w("d"), w("e"), e, PadToken, PadToken, PadToken}},
+		},
+		{
+			name:    "padding of overflowing windows",
+			options: []EncodeOption{WithMaxLength(6), WithPadding(PadLeft), WithPadToken("[PAD]")},
+			expected: [][]string{
+				{"[PAD]", s, w("a"), w("b"), w("c"), e},
+				{"[PAD]", "[PAD]", s, w("d"), w("e"), e},
+			},
+		},
+		{
+			name:     "pad to multiple of",
+			options:  []EncodeOption{WithPadding(PadRight), WithPadToMultipleOf(4)},
+			expected: [][]string{{s, w("a"), w("b"), w("c"), e, s, w("d"), w("e"), e, PadToken, PadToken, PadToken}},
+		},
+	}
+
+	model := newTruncationModel()
+
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			encoding, err := model.EncodeDetailed(strings.NewReader("a b c\nd e"), tc.options...)
+			if tc.withError {
+				if err == nil {
+					t.Error("Error expected")
+				}
+
+				return
+			}
+
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+
+			actual := [][]string{encoding.Strings()}
+			for _, overflowing := range encoding.Overflowing {
+				actual = append(actual, overflowing.Strings())
+			}
+
+			if !reflect.DeepEqual(tc.expected, actual) {
+				t.Errorf("Expected: %v\nGot: %v\n", tc.expected, actual)
+			}
+
+			tokens, err := model.Encode(strings.NewReader("a b c\nd e"), tc.options...)
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+
+			if !reflect.DeepEqual(tc.expected[0], tokens) {
+				t.Errorf("Expected Encode to return the main window: %v\nGot: %v\n", tc.expected[0], tokens)
+			}
+		})
+	}
+}
+
+func TestBPE_EncodeDetailed_Padding(t *testing.T) {
+	model := newTruncationModel()
+
+	encoding, err := model.EncodeDetailed(strings.NewReader("a"), WithMaxLength(5), WithPadding(PadLeft))
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	padID, _ := model.TokenToID(PadToken)
+
+	tt := []struct {
+		name     string
+		actual   []int
+		expected []int
+	}{
+		{name: "ids", actual: encoding.IDs()[:2], expected: []int{padID, padID}},
+		{name: "attention mask", actual: encoding.AttentionMask(), expected: []int{0, 0, 1, 1, 1}},
+		{name: "special tokens mask", actual: encoding.SpecialTokensMask(), expected: []int{1, 1, 1, 0, 1}},
+		{name: "word ids", actual: encoding.WordIDs(), expected: []int{-1, -1, -1, 0, -1}},
+		{name: "sentence ids", actual: encoding.SentenceIDs(), expected: []int{-1, -1, 0, 0, 0}},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			if !reflect.DeepEqual(tc.expected, tc.actual) {
+				t.Errorf("Expected: %v\nGot: %v\n", tc.expected, tc.actual)
+			}
+		})
+	}
+}
+
+func TestBPE_EncodeDetailed_UnknownPadToken(t *testing.T) {
+	model := newTruncationModel()
+
+	tt := []string{"<missing>", BeginOfWord + "a" + EndOfWord}
+
+	for _, token := range tt {
+		t.Run(token, func(t *testing.T) {
+			_, err := model.EncodeDetailed(strings.NewReader("a"), WithPadding(PadRight), WithPadToken(token))
+			if err == nil {
+				t.Error("Error expected")
+			}
+		})
+	}
+}
+
+func TestBPE_EncodePair(t *testing.T) {
+	const s, e = BeginOfSentence, EndOfSentence
+	w := func(word string) string { return BeginOfWord + word + EndOfWord }
+
+	tt := []struct {
+		name      string
+		options   []EncodeOption
+		expected  []string
+		sequences []int
+		withError bool
+	}{
+		{
+			name:      "no truncation",
+			expected:  []string{s, w("a"), e, s, w("b"), w("c"), w("d"), w("e"), e},
+			sequences: []int{0, 0, 0, 1, 1, 1, 1, 1, 1},
+		},
+		{
+			name:      "longest first",
+			options:   []EncodeOption{WithMaxLength(6), WithTruncation(TruncateLongestFirst)},
+			expected:  []string{s, w("a"), e, s, w("b"), e},
+			sequences: []int{0, 0, 0, 1, 1, 1},
+		},
+		{
+			name:      "too short for two sequences",
+			options:   []EncodeOption{WithMaxLength(5), WithTruncation(TruncateLongestFirst)},
+			withError: true,
+		},
+		{
+			name:      "too short for two sequences with tail",
+			options:   []EncodeOption{WithMaxLength(4)},
+			withError: true,
+		},
+		{
+			name:      "tail",
+			options:   []EncodeOption{WithMaxLength(6), WithPadding(PadRight), WithPadToMultipleOf(4)},
+			expected:  []string{s, w("a"), e, s, w("b"), e, PadToken, PadToken},
+			sequences: []int{0, 0, 0, 1, 1, 1, -1, -1},
+		},
+	}
+
+	model := newTruncationModel()
+
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			encoding, err := model.EncodePair(strings.NewReader("a"), strings.NewReader("b c d e"), tc.options...)
+			if tc.withError {
+				if err == nil {
+					t.Error("Error expected")
+				}
+
+				return
+			}
+
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+
+			if !reflect.DeepEqual(tc.expected, encoding.Strings()) {
+				t.Errorf("Expected: %v\nGot: %v\n", tc.expected, encoding.Strings())
+			}
+
+			if !reflect.DeepEqual(tc.sequences, encoding.SequenceIDs()) {
+				t.Errorf("Expected sequences: %v\nGot: %v\n", tc.sequences, encoding.SequenceIDs())
+			}
+		})
+	}
+}